@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// writeAdjList writes one line per package, its import path followed by
+// a colon and the import paths it imports, all sorted, for use with grep
+// and diff.
+func writeAdjList(w io.Writer, g *graph) error {
+	for _, name := range g.nodes {
+		imps := append([]string{}, g.edges[name]...)
+		sort.Strings(imps)
+		line := name + ":"
+		if len(imps) > 0 {
+			line += " " + strings.Join(imps, " ")
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
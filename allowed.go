@@ -0,0 +1,49 @@
+package main
+
+import "flag"
+
+var allowedFile = flag.String("allowed", "", "a file listing the packages, patterns with ..., and modules the graph may contain, one per line; any other package is highlighted and reported, and godepgraph exits with a non-zero status")
+
+// checkAllowed colors every package in g that is not allowed by the
+// entries of an -allowed file red and reports it. A package is
+// allowed if it is in the root's module, if an entry names its module or
+// matches its import path, or, for the standard library, if there is an
+// entry "std". It returns the number of packages not allowed.
+func checkAllowed(g *graph, entries []string) int {
+	isFirstParty := firstParty(g)
+	std := false
+	modules := make(map[string]bool)
+	var matches []func(string) bool
+	for _, e := range entries {
+		if e == "std" {
+			std = true
+			continue
+		}
+		modules[e] = true
+		matches = append(matches, matchPattern(e))
+	}
+
+	allowed := func(name string) bool {
+		pkg := pkgs[name]
+		if isFirstParty(name) || pkg.Goroot && std || !pkg.Goroot && modules[moduleOf(pkg)] {
+			return true
+		}
+		for _, match := range matches {
+			if match(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	n := 0
+	for _, name := range g.nodes {
+		if allowed(name) {
+			continue
+		}
+		n++
+		g.setNodeAttr(name, "color", "red")
+		report("%s is not allowed\n", name)
+	}
+	return n
+}
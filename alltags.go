@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var allTags = flag.Bool("all-tags", false, "also follow the imports of files excluded by build constraints, to show everything a package could depend on under any tags or platform")
+
+// constrainedOut reports whether err only says that every Go file of pkg
+// is excluded by build constraints, which -all-tags looks past.
+func constrainedOut(pkg *build.Package, err error) bool {
+	_, ok := err.(*build.NoGoError)
+	return ok && pkg != nil && len(pkg.IgnoredGoFiles) > 0
+}
+
+// addConstrainedImports parses the Go files of pkg that the build context
+// excluded and adds their imports to those of pkg, as if every build
+// constraint were satisfied. Files declaring a different package, such as
+// generators kept out with an ignore tag, are skipped.
+func addConstrainedImports(pkg *build.Package) error {
+	fset := token.NewFileSet()
+	for _, name := range pkg.IgnoredGoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %s", name, err)
+		}
+		if pkg.Name == "" {
+			pkg.Name = strings.TrimSuffix(f.Name.Name, "_test")
+		}
+
+		var imports *[]string
+		switch {
+		case !strings.HasSuffix(name, "_test.go") && f.Name.Name == pkg.Name:
+			imports = &pkg.Imports
+		case strings.HasSuffix(name, "_test.go") && f.Name.Name == pkg.Name:
+			imports = &pkg.TestImports
+		case strings.HasSuffix(name, "_test.go") && f.Name.Name == pkg.Name+"_test":
+			imports = &pkg.XTestImports
+		default:
+			debugf("skipping %s of package %s in %s", name, f.Name.Name, pkg.ImportPath)
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if path != "C" && !hasString(*imports, path) {
+				*imports = append(*imports, path)
+			}
+		}
+	}
+	return nil
+}
+
+// hasString reports whether list holds s.
+func hasString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ancestors = flag.String("ancestors", "", "PKG,PATTERN: render the packages matching PATTERN that import PKG, directly or not, as a tree growing up from PKG")
+	impact    = flag.String("impact", "", "print the packages that a change to this package could affect: those matching the pattern given as root that import it, directly or not, and the packages in between")
+)
+
+// ancestorGraph builds the graph of the packages matching pattern and
+// reduces it to those that depend on pkgName, along with the packages
+// connecting them to it. The edges are reversed, so that the graph is
+// rooted at pkgName and points at the packages that would be affected by
+// a change to it.
+func ancestorGraph(root, arg string) (*graph, error) {
+	parts := strings.SplitN(arg, ",", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("-ancestors needs a package and a pattern, got %q", arg)
+	}
+	pkgName, pattern := parts[0], parts[1]
+
+	paths, err := expandRoots(root, pattern)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool)
+	for _, path := range paths {
+		if err := processRoot(root, path); err != nil {
+			return nil, err
+		}
+		matched[canonicalPath(path)] = true
+	}
+	g := newGraph()
+	if !g.hasNode(pkgName) {
+		return nil, fmt.Errorf("no package matching %s imports %s", pattern, pkgName)
+	}
+
+	g.transpose()
+	importers := g.reachable(pkgName)
+	var from []string
+	for name := range importers {
+		if matched[name] {
+			from = append(from, name)
+		}
+	}
+	g.transpose()
+	keep := g.reachable(from...)
+	for name := range keep {
+		keep[name] = importers[name]
+	}
+	keep[pkgName] = true
+	g.keepNodes(keep)
+
+	g.transpose()
+	g.roots = []string{pkgName}
+	g.setNodeAttr(pkgName, "shape", "box")
+	return g, nil
+}
+
+// writeImpact writes the packages of the ancestor graph g, the package that
+// changed and everything that imports it, one per line.
+func writeImpact(w io.Writer, g *graph) error {
+	for _, name := range g.nodes {
+		fmt.Fprintln(w, name)
+	}
+	infof("%d packages affected by a change to %s", len(g.nodes), strings.Join(g.roots, ", "))
+	return nil
+}
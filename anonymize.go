@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+	"sort"
+	"strings"
+)
+
+var anonymizeFile = flag.String("anonymize", "", "replace the import paths of the root's module with pseudonyms, writing the mapping to this file")
+
+// anonymize renames the packages of g that belong to the root's module to
+// pkg001, pkg002 and so on, in import path order, leaving the standard
+// library and other modules alone. The mapping from pseudonym to import
+// path is written to file. The import paths are replaced in attributes,
+// notes and comments as well, and clusters holding any of the renamed packages
+// are renamed to cluster001 and so on, as their labels are usually made
+// of the same import paths.
+func anonymize(g *graph, file string) error {
+	isFirstParty := firstParty(g)
+	names := make(map[string]string)
+	var order []string
+	for _, name := range g.nodes {
+		if !isFirstParty(name) {
+			continue
+		}
+		alias := fmt.Sprintf("pkg%03d", len(order)+1)
+		names[name] = alias
+		order = append(order, name)
+	}
+
+	for _, name := range order {
+		pkg := pkgs[name]
+		alias := names[name]
+		// Keep only what is needed for rendering, so nothing else about
+		// the package leaks into the output.
+		pkgs[alias] = &build.Package{ImportPath: alias, Name: alias, CgoFiles: pkg.CgoFiles}
+		blank := make(map[string]bool)
+		for path := range blankImports[name] {
+			if n, ok := names[canonicalPath(path)]; ok {
+				blank[n] = true
+			} else {
+				blank[path] = true
+			}
+		}
+		blankImports[alias] = blank
+	}
+	g.rename(names)
+	anonymizeText(g, names)
+
+	renamed := make(map[string]bool)
+	for _, alias := range names {
+		renamed[alias] = true
+	}
+	var clusterLabels []string
+	anonymizeClusters(g.clusters, renamed, &clusterLabels)
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, name := range order {
+		fmt.Fprintf(w, "%s %s\n", names[name], name)
+	}
+	for i, label := range clusterLabels {
+		fmt.Fprintf(w, "cluster%03d %s\n", i+1, label)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// anonymizeText replaces the import paths in names with their pseudonyms
+// wherever g spells them out in text: node and edge attributes, such as
+// the labels of the nodes -expand draws for whole modules or those of
+// -edge-reasons, notes and comments. Longer import paths are replaced
+// first, so that x/ab doesn't end up as the pseudonym of x/a followed by
+// a b.
+func anonymizeText(g *graph, names map[string]string) {
+	var paths []string
+	for name := range names {
+		paths = append(paths, name)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) > len(paths[j])
+		}
+		return paths[i] < paths[j]
+	})
+	var pairs []string
+	for _, name := range paths {
+		pairs = append(pairs, name, names[name])
+	}
+	r := strings.NewReplacer(pairs...)
+
+	replaceAttrs := func(a attrs) {
+		for i := range a {
+			a[i].value = r.Replace(a[i].value)
+		}
+	}
+	for _, a := range g.nodeAttrs {
+		replaceAttrs(a)
+	}
+	for _, a := range g.edgeAttrs {
+		replaceAttrs(a)
+	}
+	for _, notes := range g.notes {
+		for i := range notes {
+			notes[i] = r.Replace(notes[i])
+		}
+	}
+	for i := range g.comments {
+		g.comments[i] = r.Replace(g.comments[i])
+	}
+}
+
+// anonymizeClusters replaces the label of every cluster in clusters that
+// holds a renamed package, directly or in a nested cluster, with a
+// pseudonym, appending the original labels to labels in the order of the
+// pseudonyms.
+func anonymizeClusters(clusters []cluster, renamed map[string]bool, labels *[]string) {
+	for i := range clusters {
+		c := &clusters[i]
+		if holdsAny(*c, renamed) {
+			*labels = append(*labels, c.label)
+			c.label = fmt.Sprintf("cluster%03d", len(*labels))
+		}
+		anonymizeClusters(c.clusters, renamed, labels)
+	}
+}
+
+// holdsAny reports whether c or a cluster nested in it holds one of the
+// nodes in names.
+func holdsAny(c cluster, names map[string]bool) bool {
+	for _, name := range c.nodes {
+		if names[name] {
+			return true
+		}
+	}
+	for _, sub := range c.clusters {
+		if holdsAny(sub, names) {
+			return true
+		}
+	}
+	return false
+}
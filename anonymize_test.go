@@ -0,0 +1,154 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// anonymizedFixture collects the packages of a GOPATH with the packages
+// ex/app, ex/app/sub and ex/lib, and returns their graph and the file to
+// write the mapping of pseudonyms to.
+func anonymizedFixture(t *testing.T) (*graph, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go":     "package app\n\nimport (\n\t_ \"ex/app/sub\"\n\t_ \"ex/lib\"\n\t_ \"strings\"\n)\n",
+		"src/ex/app/sub/sub.go": "package sub\n",
+		"src/ex/lib/lib.go":     "package lib\n",
+	})
+	useGOPATH(t, dir)
+	return traverse(t, dir, "ex/app"), filepath.Join(t.TempDir(), "mapping")
+}
+
+// assertNoLeaks fails the test if any node or cluster of g still mentions
+// the ex/ import paths.
+func assertNoLeaks(t *testing.T, g *graph) {
+	t.Helper()
+	for _, name := range g.nodes {
+		if strings.HasPrefix(name, "ex") {
+			t.Errorf("node %s was not renamed", name)
+		}
+		if label := g.nodeAttrs[name].String(); strings.Contains(label, "ex/") {
+			t.Errorf("node %s has attributes %s", name, label)
+		}
+	}
+	var walk func(clusters []cluster)
+	walk = func(clusters []cluster) {
+		for _, c := range clusters {
+			if strings.Contains(c.label, "ex") {
+				t.Errorf("cluster %s was not renamed", c.label)
+			}
+			walk(c.clusters)
+		}
+	}
+	walk(g.clusters)
+}
+
+func TestAnonymizeTreeClusters(t *testing.T) {
+	g, file := anonymizedFixture(t)
+	clusterTree(g)
+	if err := anonymize(g, file); err != nil {
+		t.Fatal(err)
+	}
+	assertNoLeaks(t, g)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "pkg001 ex/app\npkg002 ex/app/sub\npkg003 ex/lib\ncluster001 ex\ncluster002 app\n"
+	if string(data) != want {
+		t.Errorf("mapping = %q, want %q", data, want)
+	}
+}
+
+func TestAnonymizeGroups(t *testing.T) {
+	g, file := anonymizedFixture(t)
+	clusterGroups(g, []groupRule{
+		{pattern: "ex/app/...", match: matchPattern("ex/app/..."), group: "ex app"},
+		{pattern: "strings", match: matchPattern("strings"), group: "std"},
+	})
+	if err := anonymize(g, file); err != nil {
+		t.Fatal(err)
+	}
+	assertNoLeaks(t, g)
+	var labels []string
+	for _, c := range g.clusters {
+		labels = append(labels, c.label)
+	}
+	if got, want := strings.Join(labels, " "), "cluster001 cluster002 std"; got != want {
+		t.Errorf("cluster labels = %s, want %s", got, want)
+	}
+}
+
+func TestAnonymizeNodeLabels(t *testing.T) {
+	g, file := anonymizedFixture(t)
+	g.setNodeAttr("ex/lib", "label", "ex/lib (2 packages)")
+	g.setNodeAttr("ex/lib", "tooltip", "ex/lib")
+	if err := anonymize(g, file); err != nil {
+		t.Fatal(err)
+	}
+	assertNoLeaks(t, g)
+	if got, want := g.nodeAttrs["pkg003"].String(), `label="pkg003 (2 packages)" tooltip="pkg003"`; got != want {
+		t.Errorf("attributes = %s, want %s", got, want)
+	}
+}
+
+func TestAnonymizeWorkspaceClusters(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.work":         "go 1.18\n\nuse (\n\t./ma\n\t./mb\n)\n",
+		"ma/go.mod":       "module example.com/ma\n\ngo 1.18\n",
+		"ma/cmd/main.go":  "package main\n\nimport _ \"example.com/mb/util\"\n\nfunc main() {}\n",
+		"mb/go.mod":       "module example.com/mb\n\ngo 1.18\n",
+		"mb/util/util.go": "package util\n",
+	})
+	useModules(t)
+	chdir(t, filepath.Join(dir, "ma"))
+
+	g := traverse(t, filepath.Join(dir, "ma"), "example.com/ma/cmd")
+	if err := clusterWorkspace(g, dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := anonymize(g, filepath.Join(t.TempDir(), "mapping")); err != nil {
+		t.Fatal(err)
+	}
+	var labels []string
+	for _, c := range g.clusters {
+		labels = append(labels, c.label)
+	}
+	if got, want := strings.Join(labels, " "), "cluster001 example.com/mb"; got != want {
+		t.Errorf("cluster labels = %s, want %s", got, want)
+	}
+}
+
+func TestAnonymizeText(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		dir := t.TempDir()
+		writeFiles(t, dir, map[string]string{
+			"src/x/a/a.go":   "package a\n\nimport _ \"x/ab\"\n",
+			"src/x/ab/ab.go": "package ab\n",
+		})
+		useGOPATH(t, dir)
+		g := traverse(t, dir, "x/a")
+		g.addNote("x/a", "(+1 hidden: x/ab)")
+		g.setEdgeAttr("x/a", "x/ab", "label", "x/a imports x/ab")
+		g.comments = append(g.comments, "x/ab is deprecated")
+
+		if err := anonymize(g, filepath.Join(t.TempDir(), "mapping")); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := g.notes["pkg001"], []string{"(+1 hidden: pkg002)"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("notes = %q, want %q", got, want)
+		}
+		if got, want := g.edgeAttrs[edge{"pkg001", "pkg002"}].String(), `label="pkg001 imports pkg002"`; got != want {
+			t.Fatalf("edge attributes = %s, want %s", got, want)
+		}
+		if got, want := g.comments, []string{"pkg002 is deprecated"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("comments = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"debug/buildinfo"
+	"flag"
+	"fmt"
+	"go/build"
+)
+
+var binaryFile = flag.String("binary", "", "graph the module dependencies embedded in a compiled Go binary instead of a package")
+
+// binaryGraph builds a module-level graph from the build information
+// embedded in the Go binary at path. The binary only records which
+// modules were linked in, not which of them import which, so the graph is
+// the main module pointing at each of its dependencies.
+func binaryGraph(path string) (*graph, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read build info from %s: %s", path, err)
+	}
+
+	main := &build.Package{ImportPath: info.Main.Path}
+	if main.ImportPath == "" {
+		main.ImportPath = info.Path
+	}
+	pkgs[main.ImportPath] = main
+	roots = append(roots, main.ImportPath)
+
+	labels := make(map[string]string)
+	labels[main.ImportPath] = moduleLabel(main.ImportPath, info.Main.Version)
+	for _, dep := range info.Deps {
+		main.Imports = append(main.Imports, dep.Path)
+		pkgs[dep.Path] = &build.Package{ImportPath: dep.Path}
+		label := moduleLabel(dep.Path, dep.Version)
+		if dep.Replace != nil {
+			label += " => " + moduleLabel(dep.Replace.Path, dep.Replace.Version)
+		}
+		labels[dep.Path] = label
+	}
+
+	g := newGraph()
+	for _, name := range g.nodes {
+		g.setNodeAttr(name, "label", labels[name])
+	}
+	return g, nil
+}
+
+// moduleLabel formats a module path and version as path@version.
+func moduleLabel(path, version string) string {
+	if version == "" || version == "(devel)" {
+		return path
+	}
+	return path + "@" + version
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+var (
+	// aliases maps import paths that did not end up as keys in pkgs to
+	// the import path of the package they resolved to. This covers both
+	// paths that go/build reports under a different ImportPath and paths
+	// that resolve to the directory of a package already seen, e.g.
+	// through a symlink.
+	aliases = make(map[string]string)
+
+	// pkgDirs maps the directory of each package in pkgs, with symlinks
+	// resolved, to its import path.
+	pkgDirs  = make(map[string]string)
+	dirInfos = make(map[string]os.FileInfo)
+
+	// dirDupes maps the import paths found to resolve to the directory of
+	// a package already in pkgs to the import path of that package.
+	dirDupes = make(map[string]string)
+)
+
+// resetPackages forgets every package collected so far, so that a new
+// traversal can start from scratch.
+func resetPackages() {
+	pkgs = make(map[string]*build.Package)
+	blankImports = make(map[string]map[string]bool)
+	roots = nil
+	aliases = make(map[string]string)
+	pkgDirs = make(map[string]string)
+	dirInfos = make(map[string]os.FileInfo)
+	dirDupes = make(map[string]string)
+	syntheticModules = make(map[string]string)
+	pendingEdges = make(map[string][]string)
+	streamedEdges = make(map[edge]bool)
+}
+
+// canonicalPath returns the import path that the package imported as
+// importPath is keyed under in pkgs.
+func canonicalPath(importPath string) string {
+	if c, ok := aliases[importPath]; ok {
+		return c
+	}
+	return importPath
+}
+
+// resolvedImportPath returns the import path of pkg. Packages imported
+// by a relative path outside of GOPATH keep that relative path as their
+// ImportPath; for those the path is derived from the enclosing module
+// instead, so that the package isn't graphed twice when it is also
+// imported by its real path.
+func resolvedImportPath(pkg *build.Package) string {
+	if !build.IsLocalImport(pkg.ImportPath) || pkg.Dir == "" {
+		return pkg.ImportPath
+	}
+	modFile := findGoMod(pkg.Dir)
+	if modFile == "" {
+		return pkg.ImportPath
+	}
+	data, err := os.ReadFile(modFile)
+	if err != nil {
+		return pkg.ImportPath
+	}
+	mod := modulePath(data)
+	rel, err := filepath.Rel(filepath.Dir(modFile), pkg.Dir)
+	if mod == "" || err != nil {
+		return pkg.ImportPath
+	}
+	return path.Join(mod, filepath.ToSlash(rel))
+}
+
+// seenDir returns the import path of a package already in pkgs that lives
+// in the same directory as pkg, if there is one, and otherwise records
+// the directory of pkg.
+func seenDir(pkg *build.Package) (string, bool) {
+	if pkg.Dir == "" {
+		return "", false
+	}
+	dir, err := filepath.EvalSymlinks(pkg.Dir)
+	if err != nil {
+		dir = pkg.Dir
+	}
+	if other, ok := pkgDirs[dir]; ok {
+		return other, true
+	}
+
+	// Different spellings of a directory on a case-insensitive file
+	// system survive EvalSymlinks, so compare the files themselves.
+	info, err := os.Stat(dir)
+	if err == nil {
+		for otherDir, otherInfo := range dirInfos {
+			if os.SameFile(info, otherInfo) {
+				return pkgDirs[otherDir], true
+			}
+		}
+		dirInfos[dir] = info
+	}
+	pkgDirs[dir] = pkg.ImportPath
+	return "", false
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSameDirectoryIsOneNode(t *testing.T) {
+	tests := []struct {
+		name string
+		// imp is the second import path of ex/lib, imported by ex/app
+		// next to ex/lib itself.
+		imp string
+		// link is a symlink to target making imp resolve to the
+		// directory of ex/lib, both relative to the temporary directory.
+		link, target string
+		// gopath lists the GOPATH entries, relative to the temporary
+		// directory.
+		gopath []string
+	}{
+		{
+			name:   "symlinked directory",
+			imp:    "ex/link",
+			link:   "gp/src/ex/link",
+			target: "gp/src/ex/lib",
+			gopath: []string{"gp"},
+		},
+		{
+			name:   "second GOPATH entry",
+			imp:    "other/lib",
+			link:   "gp2/src/other",
+			target: "gp/src/ex",
+			gopath: []string{"gp", "gp2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"gp/src/ex/app/app.go": "package app\n\nimport (\n\t_ \"ex/lib\"\n\t_ \"" + tt.imp + "\"\n)\n",
+				"gp/src/ex/lib/lib.go": "package lib\n",
+			})
+			link := filepath.Join(dir, filepath.FromSlash(tt.link))
+			if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(filepath.Join(dir, filepath.FromSlash(tt.target)), link); err != nil {
+				t.Skip("symlinks not supported:", err)
+			}
+			var gopath []string
+			for _, p := range tt.gopath {
+				gopath = append(gopath, filepath.Join(dir, p))
+			}
+			useGOPATH(t, strings.Join(gopath, string(os.PathListSeparator)))
+
+			g := traverse(t, dir, "ex/app")
+			if want := []string{"ex/app", "ex/lib"}; !reflect.DeepEqual(g.nodes, want) {
+				t.Errorf("nodes = %v, want %v", g.nodes, want)
+			}
+			assertEdges(t, g, map[string][]string{"ex/app": {"ex/lib"}})
+			if got := dirDupes[tt.imp]; got != "ex/lib" {
+				t.Errorf("dirDupes[%q] = %q, want \"ex/lib\"", tt.imp, got)
+			}
+		})
+	}
+}
+
+func TestRelativeRootIsOneNode(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"gp/src/ex/app/app.go":     "package app\n\nimport _ \"ex/lib\"\n",
+		"gp/src/ex/lib/lib.go":     "package lib\n\nimport _ \"ex/app/sub\"\n",
+		"gp/src/ex/app/sub/sub.go": "package sub\n",
+	})
+	useGOPATH(t, filepath.Join(dir, "gp"))
+
+	g := traverse(t, filepath.Join(dir, "gp", "src", "ex"), "./app")
+	if want := []string{"ex/app", "ex/app/sub", "ex/lib"}; !reflect.DeepEqual(g.nodes, want) {
+		t.Errorf("nodes = %v, want %v", g.nodes, want)
+	}
+	if want := []string{"ex/app"}; !reflect.DeepEqual(g.roots, want) {
+		t.Errorf("roots = %v, want %v", g.roots, want)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+var (
+	chunkSize = flag.Int("chunk", 0, "split the packages matching the pattern given as root into groups of this many, and write the graph of each group to its own file")
+	chunkOut  = flag.String("out", "chunk-%d.dot", "with -chunk, the name of the file to write each group to, where %d is replaced by the number of the group")
+)
+
+// writeChunks expands pattern and graphs the packages it matches n at a
+// time, each group from scratch, writing the graph of the i-th group to
+// the file named by -out. It reports whether a check failed for any of
+// them.
+func writeChunks(root, pattern string, n int) (bool, error) {
+	paths, err := expandRoots(root, pattern)
+	if err != nil {
+		return false, err
+	}
+	failed := false
+	for i := 0; i*n < len(paths); i++ {
+		chunk := paths[i*n:]
+		if len(chunk) > n {
+			chunk = chunk[:n]
+		}
+
+		resetPackages()
+		ids = make(map[string]int)
+		nextId = 0
+		for _, path := range chunk {
+			if err := processRoot(root, path); err != nil {
+				return false, err
+			}
+		}
+
+		name := fmt.Sprintf(*chunkOut, i+1)
+		f, err := os.Create(name)
+		if err != nil {
+			return false, err
+		}
+		if writeGraph(f, newGraph(), root) {
+			failed = true
+		}
+		if err := f.Close(); err != nil {
+			return false, err
+		}
+		infof("wrote %d packages to %s", len(chunk), name)
+	}
+	return failed, nil
+}
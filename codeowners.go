@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var codeowners = flag.String("codeowners", "", "only render the packages this CODEOWNERS owner, such as @org/team, owns, and the packages they import")
+
+// An ownerRule is a line of a CODEOWNERS file.
+type ownerRule struct {
+	line    int
+	pattern string
+	owners  []string
+}
+
+// findCodeowners looks for a CODEOWNERS file in dir and its parents, in
+// the places GitHub looks for one, and returns its path and the directory
+// its patterns are relative to.
+func findCodeowners(dir string) (file, root string, err error) {
+	for {
+		for _, sub := range []string{".github", ".", "docs"} {
+			file := filepath.Join(dir, sub, "CODEOWNERS")
+			if _, err := os.Stat(file); err == nil {
+				return file, dir, nil
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", fmt.Errorf("no CODEOWNERS file found")
+		}
+		dir = parent
+	}
+}
+
+// readCodeowners parses the CODEOWNERS file at path.
+func readCodeowners(path string) ([]ownerRule, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rules []ownerRule
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var owners []string
+		for _, o := range fields[1:] {
+			if strings.HasPrefix(o, "#") {
+				break
+			}
+			owners = append(owners, o)
+		}
+		rules = append(rules, ownerRule{n, fields[0], owners})
+	}
+	return rules, s.Err()
+}
+
+// ownerOf returns the last rule matching file, a slash-separated path
+// relative to the root of the CODEOWNERS file, or nil if none does.
+func ownerOf(rules []ownerRule, file string) *ownerRule {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if matchOwnerPattern(rules[i].pattern, file) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// matchOwnerPattern reports whether the gitignore-style pattern of a
+// CODEOWNERS rule matches file or one of the directories it is in.
+func matchOwnerPattern(pattern, file string) bool {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.Trim(pattern, "/")
+	pat := strings.Split(pattern, "/")
+	parts := strings.Split(file, "/")
+
+	for start := 0; start < len(parts); start++ {
+		if anchored && start > 0 {
+			break
+		}
+		for end := start + 1; end <= len(parts); end++ {
+			if dirOnly && end == len(parts) {
+				break
+			}
+			if matchSegments(pat, parts[start:end]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, where
+// "**" matches any number of segments.
+func matchSegments(pat, parts []string) bool {
+	if len(pat) == 0 {
+		return len(parts) == 0
+	}
+	if pat[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pat[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pat[0], parts[0]); !ok {
+		return false
+	}
+	return matchSegments(pat[1:], parts[1:])
+}
+
+// keepOwned reduces g to the packages that owner owns according to the
+// CODEOWNERS file found above the root package, and the packages they
+// import, which are drawn dashed as the boundary of the team's code. The
+// owner of every package of the repository is reported.
+func keepOwned(g *graph, owner string) error {
+	if len(g.roots) == 0 {
+		return nil
+	}
+	file, root, err := findCodeowners(pkgs[g.roots[0]].Dir)
+	if err != nil {
+		return err
+	}
+	rules, err := readCodeowners(file)
+	if err != nil {
+		return err
+	}
+
+	owned := make(map[string]bool)
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		rel, err := filepath.Rel(root, pkg.Dir)
+		if err != nil || pkg.Goroot || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		// Rules match files, so ask about one of the package's.
+		f := filepath.ToSlash(rel) + "/"
+		if files := sourceFiles(pkg); len(files) > 0 {
+			f += files[0]
+		}
+		f = strings.TrimPrefix(f, "./")
+		rule := ownerOf(rules, f)
+		if rule == nil {
+			report("%s: no owner\n", name)
+			continue
+		}
+		report("%s: %s (%s:%d %s)\n", name, strings.Join(rule.owners, " "), file, rule.line, rule.pattern)
+		for _, o := range rule.owners {
+			if o == owner {
+				owned[name] = true
+			}
+		}
+	}
+	if len(owned) == 0 {
+		return fmt.Errorf("%s owns no package in the graph", owner)
+	}
+
+	keep := make(map[string]bool)
+	for name := range owned {
+		keep[name] = true
+		for _, imp := range g.edges[name] {
+			keep[imp] = true
+		}
+	}
+	g.filterEdges(func(from, to string) bool {
+		return owned[from]
+	})
+	g.keepNodes(keep)
+	for _, name := range g.nodes {
+		if !owned[name] {
+			g.setNodeAttr(name, "style", *nodeStyle+",dashed")
+		}
+	}
+	return nil
+}
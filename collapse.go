@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"strings"
+)
+
+var (
+	collapseStdlib = flag.Bool("collapse-stdlib", false, "draw all standard library packages as a single node")
+	collapseCgo    = flag.Bool("collapse-cgo", false, "draw all packages using cgo as a single node")
+	expand         = flag.String("expand", "", "only draw the packages matching this pattern, where ... matches anything, individually; draw the others as one node per module")
+)
+
+// stdNode is the name of the node standing in for the standard library
+// when it is collapsed.
+const stdNode = "std"
+
+// cgoNode is the name of the node standing in for the packages using cgo
+// when they are collapsed.
+const cgoNode = "cgo"
+
+// collapseStd folds the standard library packages in g into a single node
+// labelled with the number of packages it represents.
+func collapseStd(g *graph) {
+	counts := g.collapse(func(name string) string {
+		if pkgs[name].Goroot {
+			return stdNode
+		}
+		return ""
+	})
+	if counts[stdNode] == 0 {
+		return
+	}
+	pkgs[stdNode] = &build.Package{ImportPath: stdNode, Goroot: true}
+	g.setNodeAttr(stdNode, "label", fmt.Sprintf("stdlib (%d packages)", counts[stdNode]))
+}
+
+// collapseCgoPackages folds the packages in g that use cgo into a single
+// node labelled with the number of packages it represents.
+func collapseCgoPackages(g *graph) {
+	counts := g.collapse(func(name string) string {
+		if len(pkgs[name].CgoFiles) > 0 {
+			return cgoNode
+		}
+		return ""
+	})
+	if counts[cgoNode] == 0 {
+		return
+	}
+	pkgs[cgoNode] = &build.Package{ImportPath: cgoNode, CgoFiles: []string{"C"}}
+	g.setNodeAttr(cgoNode, "label", fmt.Sprintf("cgo (%d packages)", counts[cgoNode]))
+}
+
+// facades lists the packages given with -facade.
+var facades stringsFlag
+
+func init() {
+	flag.Var(&facades, "facade", "draw the packages below this package as part of it, so that imports of them end at it; may be given several times")
+}
+
+// collapseFacades folds every package in g whose import path is below one
+// of facades into that package, so that the edges into its internals end
+// at the facade and the internals are hidden. The innermost facade wins;
+// facades that are not in g are ignored.
+func collapseFacades(g *graph, facades []string) {
+	counts := g.collapse(func(name string) string {
+		best := ""
+		for _, f := range facades {
+			if !g.hasNode(f) {
+				continue
+			}
+			if (name == f || strings.HasPrefix(name, f+"/")) && len(f) > len(best) {
+				best = f
+			}
+		}
+		return best
+	})
+	for _, f := range facades {
+		if n := counts[f]; n > 0 {
+			g.addNote(f, fmt.Sprintf("(+%d hidden)", n))
+		}
+	}
+}
+
+// collapseUnexpanded folds the packages in g that don't match pattern into
+// a node per module, named after the module with a "/..." suffix, and the
+// standard library packages into a single node, each labelled with the
+// number of packages it represents. Packages whose module is unknown are
+// left alone.
+func collapseUnexpanded(g *graph, pattern string) {
+	match := matchPattern(pattern)
+	counts := g.collapse(func(name string) string {
+		pkg := pkgs[name]
+		switch {
+		case match(name):
+			return ""
+		case pkg.Goroot:
+			return stdNode
+		}
+		if mod := moduleOf(pkg); mod != "" {
+			return mod + "/..."
+		}
+		return ""
+	})
+	for key, n := range counts {
+		if key == stdNode {
+			pkgs[stdNode] = &build.Package{ImportPath: stdNode, Goroot: true}
+			g.setNodeAttr(stdNode, "label", fmt.Sprintf("stdlib (%d packages)", n))
+			continue
+		}
+		pkgs[key] = &build.Package{ImportPath: key}
+		syntheticModules[key] = strings.TrimSuffix(key, "/...")
+		g.setNodeAttr(key, "label", fmt.Sprintf("%s (%d packages)", key, n))
+	}
+}
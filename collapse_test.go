@@ -0,0 +1,42 @@
+package main
+
+import (
+	"go/build"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCollapseUnexpanded(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go":   "package app\n\nimport (\n\t_ \"ex/lib\"\n\t_ \"ex/util\"\n\t_ \"strings\"\n)\n",
+		"src/ex/lib/lib.go":   "package lib\n",
+		"src/ex/util/util.go": "package util\n",
+	})
+	useGOPATH(t, dir)
+	g := traverse(t, dir, "ex/app")
+	// A package without a directory, as read from a binary, has no
+	// known module.
+	pkgs["nodir"] = &build.Package{ImportPath: "nodir"}
+	g.nodes = append(g.nodes, "nodir")
+	g.edges["ex/app"] = append(g.edges["ex/app"], "nodir")
+
+	collapseUnexpanded(g, "ex/app")
+	if want := []string{"GOPATH/...", "ex/app", "nodir", stdNode}; !reflect.DeepEqual(g.nodes, want) {
+		t.Errorf("nodes = %v, want %v", g.nodes, want)
+	}
+	if got := moduleOf(pkgs["GOPATH/..."]); got != gopathModule {
+		t.Errorf("module of GOPATH/... = %q, want %q", got, gopathModule)
+	}
+	if !firstParty(g)("GOPATH/...") {
+		t.Error("GOPATH/... is not first-party")
+	}
+
+	if err := anonymize(g, filepath.Join(t.TempDir(), "mapping")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := g.nodeAttrs["pkg001"].String(), `label="pkg001 (2 packages)"`; got != want {
+		t.Errorf("attributes of the anonymized GOPATH/... = %s, want %s", got, want)
+	}
+}
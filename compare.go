@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+var compare = flag.String("compare", "", "two comma-separated packages A,B; print the dependencies only A has, only B has, and both share instead of a graph")
+
+// compared returns the two packages given with -compare.
+func compared() (string, string, error) {
+	parts := strings.Split(*compare, ",")
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("-compare needs two comma-separated packages, got %q", *compare)
+	}
+	return parts[0], parts[1], nil
+}
+
+// processCompare collects the packages reachable from both of the
+// packages given with -compare.
+func processCompare(root string) error {
+	a, b, err := compared()
+	if err != nil {
+		return err
+	}
+	if err := processRoot(root, a); err != nil {
+		return err
+	}
+	return processRoot(root, b)
+}
+
+// writeCompare writes the dependencies of the first root of g that the
+// second lacks, those of the second that the first lacks, and those they
+// share.
+func writeCompare(w io.Writer, g *graph) error {
+	if len(g.roots) != 2 {
+		return fmt.Errorf("-compare needs two distinct packages in the graph")
+	}
+	a, b := g.roots[0], g.roots[1]
+	depsA, depsB := g.reachable(a), g.reachable(b)
+	delete(depsA, a)
+	delete(depsB, b)
+
+	var onlyA, onlyB, shared []string
+	for name := range depsA {
+		if depsB[name] {
+			shared = append(shared, name)
+		} else {
+			onlyA = append(onlyA, name)
+		}
+	}
+	for name := range depsB {
+		if !depsA[name] {
+			onlyB = append(onlyB, name)
+		}
+	}
+
+	writeSet(w, "only "+a, onlyA)
+	writeSet(w, "only "+b, onlyB)
+	writeSet(w, "shared", shared)
+	return nil
+}
+
+// writeSet writes a heading followed by the sorted, indented names.
+func writeSet(w io.Writer, heading string, names []string) {
+	sort.Strings(names)
+	fmt.Fprintf(w, "%s (%d):\n", heading, len(names))
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%s\n", name)
+	}
+}
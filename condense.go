@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var (
+	condense     = flag.Bool("condense", false, "draw each import cycle as a single node, leaving a graph without cycles")
+	cycleContext = flag.Int("cycle-context", -1, "only render the import cycles, each in its own box, and the packages within this many imports of them")
+)
+
+// condenseCycles folds every strongly connected component of g into a
+// single node, named after its first package, labelled with the number of
+// packages in it and listing them in its tooltip.
+func condenseCycles(g *graph) {
+	group := make(map[string]string)
+	for _, scc := range g.cycles() {
+		for _, name := range scc {
+			group[name] = scc[0]
+		}
+		g.setNodeAttr(scc[0], "label", fmt.Sprintf("cycle of %d packages", len(scc)))
+		g.setNodeAttr(scc[0], "tooltip", strings.Join(scc, `\n`))
+		g.setNodeAttr(scc[0], "shape", "doubleoctagon")
+	}
+	g.collapse(func(name string) string {
+		return group[name]
+	})
+}
+
+// keepCycleContext reduces g to its import cycles, each drawn red in a
+// cluster of its own, and the packages at most hops imports away from
+// them in either direction. It returns the number of cycles.
+func keepCycleContext(g *graph, hops int) int {
+	importers := make(map[string][]string)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			importers[imp] = append(importers[imp], name)
+		}
+	}
+
+	cycles := g.cycles()
+	keep := make(map[string]bool)
+	inCycle := make(map[string]int)
+	g.clusters = nil
+	for i, scc := range cycles {
+		g.clusters = append(g.clusters, cluster{label: fmt.Sprintf("cycle %d", i+1), nodes: scc})
+		// Each cycle gets its own walk, so that a package already kept
+		// near an earlier cycle is still walked through.
+		frontier := scc
+		seen := make(map[string]bool)
+		for _, name := range scc {
+			seen[name] = true
+			inCycle[name] = i + 1
+		}
+		for h := 0; h < hops; h++ {
+			var next []string
+			for _, name := range frontier {
+				for _, n := range append(append([]string{}, g.edges[name]...), importers[name]...) {
+					if !seen[n] {
+						seen[n] = true
+						next = append(next, n)
+					}
+				}
+			}
+			frontier = next
+		}
+		for name := range seen {
+			keep[name] = true
+		}
+	}
+	g.keepNodes(keep)
+
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			if inCycle[name] != 0 && inCycle[name] == inCycle[imp] {
+				g.setEdgeAttr(name, imp, "color", "red")
+			}
+		}
+	}
+	return len(cycles)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeepCycleContext(t *testing.T) {
+	// x is two hops away from the cycle a-b but only one from c-d, so z
+	// is within two hops of c-d.
+	g := emptyGraph()
+	g.nodes = []string{"a", "b", "c", "d", "w", "x", "y", "z"}
+	g.edges = map[string][]string{
+		"a": {"b", "w"},
+		"b": {"a"},
+		"c": {"d", "x"},
+		"d": {"c"},
+		"w": {"x"},
+		"x": {"z"},
+		"z": {"y"},
+	}
+
+	if n := keepCycleContext(g, 2); n != 2 {
+		t.Errorf("keepCycleContext() = %d, want 2", n)
+	}
+	if want := []string{"a", "b", "c", "d", "w", "x", "z"}; !reflect.DeepEqual(g.nodes, want) {
+		t.Errorf("nodes = %v, want %v", g.nodes, want)
+	}
+}
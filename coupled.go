@@ -0,0 +1,43 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+var coupled = flag.Bool("coupled", false, "list on stderr the groups of packages that are always imported together, by the same two or more packages, as candidates for merging")
+
+// reportCoupled lists the groups of packages in g, outside of the
+// standard library, that have exactly the same importers, and at least
+// two of them. Each group is printed on a line of its own after the
+// number of importers it shares.
+func reportCoupled(g *graph) {
+	importers := make(map[string][]string)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			importers[imp] = append(importers[imp], name)
+		}
+	}
+
+	groups := make(map[string][]string)
+	var keys []string
+	for _, name := range g.nodes {
+		from := importers[name]
+		if len(from) < 2 || pkgs[name].Goroot {
+			continue
+		}
+		sort.Strings(from)
+		key := strings.Join(from, " ")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], name)
+	}
+
+	for _, key := range keys {
+		if group := groups[key]; len(group) > 1 {
+			report("%5d %s\n", len(importers[group[0]]), strings.Join(group, " "))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+)
+
+var (
+	symbolCoupling = flag.Bool("symbol-coupling", false, "label each edge with the number of distinct identifiers used from the imported package, and scale its width accordingly")
+	layoutWeights  = flag.Bool("layout-weights", false, "weight each edge by the number of distinct identifiers used from the imported package, so that the layout keeps tightly coupled packages close")
+)
+
+// symbolUses returns, for each package imported by pkg, the set of its
+// exported identifiers that pkg refers to. Dot imports aren't resolved
+// without type information, so they are not counted.
+func symbolUses(pkg *build.Package) (map[string]map[string]bool, error) {
+	files := sourceFiles(pkg)
+
+	uses := make(map[string]map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", name, err)
+		}
+
+		// Map the names the imports are known by in this file to their
+		// canonical paths.
+		names := make(map[string]string)
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			imp := canonicalPath(path)
+			var local string
+			switch {
+			case spec.Name != nil:
+				local = spec.Name.Name
+			case pkgs[imp] != nil:
+				local = pkgs[imp].Name
+			default:
+				local = filepath.Base(path)
+			}
+			if local != "_" && local != "." {
+				names[local] = imp
+			}
+		}
+
+		ast.Inspect(f, func(n ast.Node) bool {
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			// Identifiers resolved within the file are local variables
+			// etc. shadowing the import.
+			x, ok := sel.X.(*ast.Ident)
+			if !ok || x.Obj != nil {
+				return true
+			}
+			if imp, ok := names[x.Name]; ok {
+				if uses[imp] == nil {
+					uses[imp] = make(map[string]bool)
+				}
+				uses[imp][sel.Sel.Name] = true
+			}
+			return true
+		})
+	}
+	return uses, nil
+}
+
+// symbolCounts returns the number of distinct identifiers the importer
+// uses from the imported package for every edge of g, along with the
+// largest of them.
+func symbolCounts(g *graph) (map[edge]int, int, error) {
+	counts := make(map[edge]int)
+	max := 0
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		if len(g.edges[name]) == 0 || pkg.Dir == "" {
+			continue
+		}
+		uses, err := symbolUses(pkg)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, imp := range g.edges[name] {
+			n := len(uses[imp])
+			counts[edge{name, imp}] = n
+			if n > max {
+				max = n
+			}
+		}
+	}
+	return counts, max, nil
+}
+
+// markSymbolCoupling labels every edge of g with the number of distinct
+// identifiers the importer uses from the imported package, scaling the
+// width of the edge with it.
+func markSymbolCoupling(g *graph) error {
+	counts, max, err := symbolCounts(g)
+	if err != nil {
+		return err
+	}
+	for e, n := range counts {
+		if n == 0 {
+			continue
+		}
+		g.setEdgeAttr(e.from, e.to, "penwidth", fmt.Sprintf("%.1f", 1+7*float64(n)/float64(max)))
+		g.setEdgeAttr(e.from, e.to, "label", fmt.Sprint(n))
+	}
+	return nil
+}
+
+// markLayoutWeights sets the weight of every edge of g to one more than
+// the number of identifiers the importer uses from the imported package,
+// so that layout engines pull tightly coupled packages closer together.
+func markLayoutWeights(g *graph) error {
+	counts, _, err := symbolCounts(g)
+	if err != nil {
+		return err
+	}
+	for e, n := range counts {
+		g.setEdgeAttr(e.from, e.to, "weight", fmt.Sprint(1+n))
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeCypher writes the graph as Neo4j Cypher statements: a MERGE per
+// package followed by a CREATE per import, suitable for cypher-shell.
+func writeCypher(w io.Writer, g *graph) error {
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		fmt.Fprintf(w, "MERGE (p:Package {path: %s}) SET p.goroot = %t, p.cgo = %t, p.module = %s;\n",
+			strconv.Quote(name), pkg.Goroot, len(pkg.CgoFiles) > 0, strconv.Quote(moduleOf(pkg)))
+	}
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			fmt.Fprintf(w, "MATCH (a:Package {path: %s}), (b:Package {path: %s}) CREATE (a)-[:IMPORTS]->(b);\n",
+				strconv.Quote(name), strconv.Quote(imp))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import "flag"
+
+var (
+	deprecatedList   = flag.String("deprecated", "", "a comma-separated list of deprecated packages; their importers are highlighted and reported")
+	failOnDeprecated = flag.Bool("fail-on-deprecated", false, "exit with a non-zero status if any package imports a -deprecated package")
+)
+
+// checkDeprecated colors the packages in g that directly import one of the
+// packages given with -deprecated red and lists them on stderr. It returns
+// the number of offending packages.
+func checkDeprecated(g *graph) int {
+	deprecated := make(map[string]bool)
+	for _, p := range splitList(*deprecatedList) {
+		deprecated[p] = true
+	}
+
+	n := 0
+	for _, name := range g.nodes {
+		var uses []string
+		for _, imp := range getImports(pkgs[name]) {
+			if deprecated[imp] {
+				uses = append(uses, imp)
+			}
+		}
+		if len(uses) == 0 {
+			continue
+		}
+		n++
+		g.setNodeAttr(name, "color", "red")
+		for _, imp := range uses {
+			report("%s imports deprecated package %s\n", name, imp)
+		}
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"sort"
+)
+
+var diamonds = flag.Int("diamonds", 0, "report and highlight packages at the bottom of at least this many diamond dependencies")
+
+// diamond describes a package that several paths from the root converge
+// on.
+type diamond struct {
+	bottom string
+	// top is the immediate dominator of bottom: the last package that
+	// all of the converging paths have in common.
+	top string
+	// count is the number of diamonds, one for each pair of importers.
+	count int
+}
+
+// findDiamonds returns the packages of g that are the bottom of at least
+// min diamonds, most diamonds first. Every pair of importers of a package
+// closes a diamond with the package's immediate dominator at the top.
+func findDiamonds(g *graph, min int) []diamond {
+	idom := g.dominators()
+	fanin := g.fanin()
+
+	var found []diamond
+	for _, name := range g.nodes {
+		k := fanin[name]
+		if k < 2 {
+			continue
+		}
+		if n := k * (k - 1) / 2; n >= min {
+			found = append(found, diamond{bottom: name, top: idom[name], count: n})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].count > found[j].count
+	})
+	return found
+}
+
+// reportDiamonds lists the diamond bottoms found in g on stderr and colors
+// them.
+func reportDiamonds(g *graph, min int) {
+	for _, d := range findDiamonds(g, min) {
+		top := d.top
+		if top == "" {
+			top = "the roots"
+		}
+		report("%s is the bottom of %d diamonds below %s\n", d.bottom, d.count, top)
+		g.setNodeAttr(d.bottom, "color", "orchid")
+	}
+}
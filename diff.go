@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"sort"
+	"strings"
+)
+
+var goosDiff = flag.String("goos-diff", "", "two comma-separated GOOS values; graph both and color the packages found under only one of them")
+
+// Colors used when diffing two graphs: for what only the first has, only
+// the second has, and both have.
+const (
+	diffAColor      = "royalblue"
+	diffBColor      = "darkorange"
+	diffCommonColor = "gray"
+)
+
+// tagDiffGraph builds the graph of pkgName once with the tags from -tags-a
+// and once with those from -tags-b, and returns the union of the two with
+// each edge colored by which of the tag sets it appears under.
+func tagDiffGraph(root, pkgName string) (*graph, error) {
+	ctxA, ctxB := buildContext, buildContext
+	ctxA.BuildTags = withTags(splitList(*tagsA))
+	ctxB.BuildTags = withTags(splitList(*tagsB))
+	a, b, err := diffGraphs(root, pkgName, ctxA, ctxB)
+	if err != nil {
+		return nil, err
+	}
+
+	g := mergeGraphs(a, b)
+	for _, name := range g.nodes {
+		inA := make(map[string]bool)
+		for _, imp := range a.edges[name] {
+			inA[imp] = true
+		}
+		inB := make(map[string]bool)
+		for _, imp := range b.edges[name] {
+			inB[imp] = true
+		}
+		for _, imp := range g.edges[name] {
+			switch {
+			case inA[imp] && inB[imp]:
+				g.setEdgeAttr(name, imp, "color", diffCommonColor)
+			case inA[imp]:
+				g.setEdgeAttr(name, imp, "color", diffAColor)
+			default:
+				g.setEdgeAttr(name, imp, "color", diffBColor)
+			}
+		}
+	}
+	return g, nil
+}
+
+// goosDiffGraph builds the graph of pkgName once for each of the two
+// operating systems given with -goos-diff, and returns the union of the
+// two with the packages found for only one of them colored.
+func goosDiffGraph(root, pkgName string) (*graph, error) {
+	goos := strings.Split(*goosDiff, ",")
+	if len(goos) != 2 {
+		return nil, fmt.Errorf("-goos-diff needs two comma-separated GOOS values, got %q", *goosDiff)
+	}
+	ctxA, ctxB := buildContext, buildContext
+	ctxA.GOOS, ctxB.GOOS = goos[0], goos[1]
+	a, b, err := diffGraphs(root, pkgName, ctxA, ctxB)
+	if err != nil {
+		return nil, err
+	}
+
+	g := mergeGraphs(a, b)
+	for _, name := range g.nodes {
+		switch inA, inB := a.hasNode(name), b.hasNode(name); {
+		case inA && inB:
+			g.setNodeAttr(name, "color", diffCommonColor)
+		case inA:
+			g.setNodeAttr(name, "color", diffAColor)
+			g.addNote(name, "("+goos[0]+" only)")
+		default:
+			g.setNodeAttr(name, "color", diffBColor)
+			g.addNote(name, "("+goos[1]+" only)")
+		}
+	}
+	return g, nil
+}
+
+// diffGraphs builds the graph of pkgName under each of the two build
+// contexts. Afterwards pkgs holds the packages of both traversals, with
+// those of the first taking precedence; they differ only in which files
+// were selected.
+func diffGraphs(root, pkgName string, ctxA, ctxB build.Context) (*graph, *graph, error) {
+	a, pkgsA, err := graphWithContext(root, pkgName, ctxA)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, pkgsB, err := graphWithContext(root, pkgName, ctxB)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pkgs = pkgsB
+	for name, pkg := range pkgsA {
+		pkgs[name] = pkg
+	}
+	return a, b, nil
+}
+
+// mergeGraphs returns the union of a and b. The edges of each node are
+// those of a followed by those only b has.
+func mergeGraphs(a, b *graph) *graph {
+	g := emptyGraph()
+	seen := make(map[string]bool)
+	for _, name := range append(a.nodes, b.nodes...) {
+		if !seen[name] {
+			seen[name] = true
+			g.nodes = append(g.nodes, name)
+		}
+	}
+	sort.Strings(g.nodes)
+	g.roots = a.roots
+
+	for _, name := range g.nodes {
+		inA := make(map[string]bool)
+		for _, imp := range a.edges[name] {
+			inA[imp] = true
+			g.edges[name] = append(g.edges[name], imp)
+		}
+		for _, imp := range b.edges[name] {
+			if !inA[imp] {
+				g.edges[name] = append(g.edges[name], imp)
+			}
+		}
+	}
+	return g
+}
+
+// graphWithContext runs a fresh traversal of pkgName using ctx and returns
+// the resulting graph along with the packages it collected.
+func graphWithContext(root, pkgName string, ctx build.Context) (*graph, map[string]*build.Package, error) {
+	resetPackages()
+	saved := buildContext
+	buildContext = ctx
+	defer func() { buildContext = saved }()
+	if err := processRoot(root, pkgName); err != nil {
+		return nil, nil, err
+	}
+	return newGraph(), pkgs, nil
+}
+
+// withTags returns the tags given with -tags followed by extra.
+func withTags(extra []string) []string {
+	tags := make([]string, 0, len(buildTags)+len(extra))
+	tags = append(tags, buildTags...)
+	return append(tags, extra...)
+}
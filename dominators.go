@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"sort"
+)
+
+var dominatorTreeFlag = flag.Bool("dominators", false, "render the dominator tree of the graph: each package hangs below the last package every path to it goes through")
+
+// dominators returns the immediate dominator of every node reachable from
+// the roots of g: the last package that every path from a root to the
+// node passes through. Nodes dominated only by the roots themselves map
+// to "", which stands for a virtual entry node preceding all roots.
+//
+// It uses the iterative algorithm from Cooper, Harvey and Kennedy, "A
+// Simple, Fast Dominance Algorithm".
+func (g *graph) dominators() map[string]string {
+	// Number the nodes in reverse postorder from the virtual entry, which
+	// gets the highest number.
+	order := make(map[string]int)
+	var postorder []string
+	var visit func(string)
+	visit = func(name string) {
+		order[name] = -1
+		for _, imp := range g.edges[name] {
+			if _, ok := order[imp]; !ok {
+				visit(imp)
+			}
+		}
+		order[name] = len(postorder)
+		postorder = append(postorder, name)
+	}
+	for _, root := range g.roots {
+		if _, ok := order[root]; !ok {
+			visit(root)
+		}
+	}
+	const entry = ""
+	order[entry] = len(postorder)
+
+	preds := make(map[string][]string)
+	for _, root := range g.roots {
+		preds[root] = append(preds[root], entry)
+	}
+	for _, name := range postorder {
+		for _, imp := range g.edges[name] {
+			preds[imp] = append(preds[imp], name)
+		}
+	}
+
+	idom := map[string]string{entry: entry}
+	intersect := func(a, b string) string {
+		for a != b {
+			for order[a] < order[b] {
+				a = idom[a]
+			}
+			for order[b] < order[a] {
+				b = idom[b]
+			}
+		}
+		return a
+	}
+	for changed := true; changed; {
+		changed = false
+		for i := len(postorder) - 1; i >= 0; i-- {
+			name := postorder[i]
+			var newIdom string
+			found := false
+			for _, p := range preds[name] {
+				if _, ok := idom[p]; !ok {
+					continue
+				}
+				if !found {
+					newIdom, found = p, true
+				} else {
+					newIdom = intersect(p, newIdom)
+				}
+			}
+			if cur, ok := idom[name]; !ok || cur != newIdom {
+				idom[name] = newIdom
+				changed = true
+			}
+		}
+	}
+	delete(idom, entry)
+	return idom
+}
+
+// dominated returns the number of nodes dominated by each node, given the
+// immediate dominators computed by dominators. Every node dominates
+// itself.
+func dominated(idom map[string]string) map[string]int {
+	children := make(map[string][]string)
+	for name, d := range idom {
+		children[d] = append(children[d], name)
+	}
+	sizes := make(map[string]int)
+	var size func(string) int
+	size = func(name string) int {
+		n := 1
+		for _, c := range children[name] {
+			n += size(c)
+		}
+		sizes[name] = n
+		return n
+	}
+	for _, c := range children[""] {
+		size(c)
+	}
+	return sizes
+}
+
+// edgeImpact returns, for each edge of g, the number of packages that
+// would no longer be reachable from the roots without that edge. An edge
+// u -> v carries all paths to v exactly when every other importer of v is
+// itself only reachable through v, and then it carries everything v
+// dominates.
+func edgeImpact(g *graph) map[edge]int {
+	idom := g.dominators()
+	sizes := dominated(idom)
+
+	dominates := func(a, b string) bool {
+		for b != "" {
+			if a == b {
+				return true
+			}
+			b = idom[b]
+		}
+		return false
+	}
+
+	preds := make(map[string][]string)
+	for _, name := range g.nodes {
+		if _, ok := idom[name]; !ok {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			preds[imp] = append(preds[imp], name)
+		}
+	}
+	isRoot := make(map[string]bool)
+	for _, root := range g.roots {
+		isRoot[root] = true
+	}
+
+	impact := make(map[edge]int)
+	for to, from := range preds {
+		if isRoot[to] {
+			continue
+		}
+		for _, u := range from {
+			sole := true
+			for _, p := range from {
+				if p != u && !dominates(to, p) {
+					sole = false
+					break
+				}
+			}
+			if sole {
+				impact[edge{u, to}] = sizes[to]
+			}
+		}
+	}
+	return impact
+}
+
+// dominatorTree returns the dominator tree of g as a graph, with an edge
+// from each package to the packages it immediately dominates.
+func dominatorTree(g *graph) *graph {
+	idom := g.dominators()
+	t := emptyGraph()
+	for name := range idom {
+		t.nodes = append(t.nodes, name)
+	}
+	sort.Strings(t.nodes)
+	for _, name := range t.nodes {
+		if d := idom[name]; d != "" {
+			t.edges[d] = append(t.edges[d], name)
+		}
+	}
+	for _, children := range t.edges {
+		sort.Strings(children)
+	}
+	t.roots = g.roots
+	return t
+}
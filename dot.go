@@ -0,0 +1,242 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"regexp"
+	"strings"
+)
+
+var (
+	asSubgraph  = flag.String("as-subgraph", "", "emit a \"subgraph cluster_NAME\" block with node ids prefixed by NAME, for pasting into a larger graph")
+	fontName    = flag.String("fontname", "", "the default font of the nodes")
+	fontSize    = flag.String("fontsize", "", "the default font size of the nodes, in points")
+	nodeStyle   = flag.String("node-style", "filled", "the DOT style of the nodes, such as \"rounded,filled\"")
+	preludeFile = flag.String("prelude", "", "a file of DOT statements, such as graph and node defaults, to write verbatim at the top of the graph")
+)
+
+// prelude holds the contents of the -prelude file.
+var prelude []byte
+
+// subgraphName matches the names accepted by -as-subgraph, which end up
+// in bare DOT identifiers.
+var subgraphName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// An attr is a single DOT attribute.
+type attr struct {
+	key, value string
+}
+
+// attrs is an ordered list of DOT attributes.
+type attrs []attr
+
+// set returns a with key set to value, replacing any earlier value.
+func (a attrs) set(key, value string) attrs {
+	for i := range a {
+		if a[i].key == key {
+			a[i].value = value
+			return a
+		}
+	}
+	return append(a, attr{key, value})
+}
+
+// merge returns a with all of the attributes in b set.
+func (a attrs) merge(b attrs) attrs {
+	for _, x := range b {
+		a = a.set(x.key, x.value)
+	}
+	return a
+}
+
+func (a attrs) String() string {
+	parts := make([]string, len(a))
+	for i, x := range a {
+		parts[i] = fmt.Sprintf(`%s="%s"`, x.key, quoteDOT(x.value))
+	}
+	return strings.Join(parts, " ")
+}
+
+// dotEscapes are the characters that may follow a backslash in a DOT
+// string to form an escape sequence that Graphviz interprets, such as \n
+// and \l for line breaks or \N for the node name.
+const dotEscapes = "nlrNGETHL"
+
+// quoteDOT escapes s for use in a quoted DOT string. Backslashes are
+// escaped unless they start one of dotEscapes, which labels and tooltips
+// use on purpose, so that any other backslash, as in a Windows file
+// name, comes through as it is.
+func quoteDOT(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			b.WriteString(`\"`)
+		case c == '\\' && (i+1 == len(s) || strings.IndexByte(dotEscapes, s[i+1]) < 0):
+			b.WriteString(`\\`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// writePrelude writes the contents of the -prelude file, if any.
+func writePrelude(w io.Writer) {
+	if len(prelude) == 0 {
+		return
+	}
+	w.Write(prelude)
+	if prelude[len(prelude)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+}
+
+// writeNodeDefaults writes a node statement setting the font given by
+// -fontname and -fontsize, if any.
+func writeNodeDefaults(w io.Writer) {
+	var a attrs
+	if *fontName != "" {
+		a = a.set("fontname", *fontName)
+	}
+	if *fontSize != "" {
+		a = a.set("fontsize", *fontSize)
+	}
+	if len(a) > 0 {
+		fmt.Fprintf(w, "node [%s];\n", a)
+	}
+}
+
+// nodeId returns the DOT identifier of the named package. With
+// -as-subgraph the identifiers are prefixed so that the output of several
+// runs can be combined without collisions.
+func nodeId(name string) string {
+	return fmt.Sprintf("%s_%d", *asSubgraph, getId(name))
+}
+
+func writeDot(w io.Writer, g *graph) error {
+	if *asSubgraph != "" {
+		fmt.Fprintf(w, "subgraph cluster_%s {\n", *asSubgraph)
+		fmt.Fprintf(w, "label=\"%s\"\n", *asSubgraph)
+	} else {
+		fmt.Fprintln(w, "digraph godep {")
+	}
+	writePrelude(w)
+	writeNodeDefaults(w)
+	for _, c := range g.comments {
+		fmt.Fprintf(w, "// %s\n", c)
+	}
+	if *horizontal && *asSubgraph == "" {
+		fmt.Fprintln(w, `rankdir="LR"`)
+	}
+
+	labels, err := nodeLabels(g)
+	if err != nil {
+		return err
+	}
+
+	for _, pkgName := range g.nodes {
+		pkg := pkgs[pkgName]
+		pkgId := nodeId(pkgName)
+
+		label := pkgName
+		if l, ok := labels[pkgName]; ok {
+			label = l
+		}
+		if notes := g.notes[pkgName]; len(notes) > 0 {
+			label += " " + strings.Join(notes, " ")
+		}
+
+		node := baseNodeAttrs(pkg, label)
+		if *wrapWidth > 0 {
+			if wrapped := wrapLabel(label, *wrapWidth); wrapped != label {
+				node = node.set("label", wrapped).set("tooltip", pkgName)
+			}
+		}
+		node = node.merge(g.nodeAttrs[pkgName])
+		fmt.Fprintf(w, "%s [%s];\n", pkgId, node)
+
+		for _, imp := range g.edges[pkgName] {
+			impId := nodeId(imp)
+
+			var e attrs
+			if *markBlank && isBlankImport(pkgName, imp) {
+				e = e.set("style", "dotted")
+			}
+			e = e.merge(g.edgeAttrs[edge{pkgName, imp}])
+			if len(e) == 0 {
+				fmt.Fprintf(w, "%s -> %s;\n", pkgId, impId)
+			} else {
+				fmt.Fprintf(w, "%s -> %s [%s];\n", pkgId, impId, e)
+			}
+		}
+	}
+
+	writeClusters(w, g.clusters)
+	writeRanks(w, g.ranks)
+	if *pinRoot && len(g.roots) > 0 {
+		fmt.Fprint(w, "{ rank=source;")
+		for _, name := range g.roots {
+			fmt.Fprintf(w, " %s;", nodeId(name))
+		}
+		fmt.Fprintln(w, " }")
+	}
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+// baseNodeAttrs returns the default DOT attributes of the node for pkg,
+// colored by the kind of package it is.
+func baseNodeAttrs(pkg *build.Package, label string) attrs {
+	var color string
+	if pkg.Goroot {
+		color = "palegreen"
+	} else if len(pkg.CgoFiles) > 0 {
+		color = "darkgoldenrod1"
+	} else {
+		color = "paleturquoise"
+	}
+	return attrs{{"label", label}, {"style", *nodeStyle}, {"color", color}}
+}
+
+// writeClusters draws each cluster as a labelled box around its nodes and
+// nested clusters.
+func writeClusters(w io.Writer, clusters []cluster) {
+	n := 0
+	writeClusterList(w, clusters, &n)
+}
+
+func writeClusterList(w io.Writer, clusters []cluster, n *int) {
+	for _, c := range clusters {
+		fmt.Fprintf(w, "subgraph cluster_%s%d {\n", *asSubgraph, *n)
+		*n++
+		fmt.Fprintf(w, "%s;\n", attrs{{"label", c.label}})
+		for _, name := range c.nodes {
+			fmt.Fprintf(w, "%s;\n", nodeId(name))
+		}
+		writeClusterList(w, c.clusters, n)
+		fmt.Fprintln(w, "}")
+	}
+}
+
+// writeRanks places each group of nodes on its own rank, and chains the
+// groups together with invisible edges so they are stacked in order.
+func writeRanks(w io.Writer, ranks [][]string) {
+	var prev string
+	for _, rank := range ranks {
+		if len(rank) == 0 {
+			continue
+		}
+		fmt.Fprint(w, "{ rank=same;")
+		for _, name := range rank {
+			fmt.Fprintf(w, " %s;", nodeId(name))
+		}
+		fmt.Fprintln(w, " }")
+		if prev != "" {
+			fmt.Fprintf(w, "%s -> %s [style=\"invis\"];\n", nodeId(prev), nodeId(rank[0]))
+		}
+		prev = rank[0]
+	}
+}
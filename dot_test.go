@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestAttrsString(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{`example.com/app`, `"example.com/app"`},
+		{`say "hi"`, `"say \"hi\""`},
+		{`a\nb\lc\r`, `"a\nb\lc\r"`},
+		{`\N (+2 hidden)`, `"\N (+2 hidden)"`},
+		{`C:\src\pkg`, `"C:\\src\\pkg"`},
+		{`trailing\`, `"trailing\\"`},
+		{`quote\"`, `"quote\\\""`},
+	}
+	for _, tt := range tests {
+		if got := (attrs{{"label", tt.value}}).String(); got != "label="+tt.want {
+			t.Errorf("label %q = %s, want label=%s", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// writeDSM writes the graph as a dependency structure matrix: one row and
+// one column per package, in topological order, with an X where the row
+// package imports the column package. Imports then all fall above the
+// diagonal, except for those that are part of a cycle.
+func writeDSM(w io.Writer, g *graph) error {
+	order := g.topoOrder()
+	index := make(map[string]int)
+	nameWidth := 0
+	for i, name := range order {
+		index[name] = i
+		if len(name) > nameWidth {
+			nameWidth = len(name)
+		}
+	}
+	numWidth := len(strconv.Itoa(len(order)))
+
+	fmt.Fprintf(w, "%*s ", numWidth+1+nameWidth, "")
+	for i := range order {
+		fmt.Fprintf(w, " %*d", numWidth, i+1)
+	}
+	fmt.Fprintln(w)
+
+	for i, name := range order {
+		row := make([]string, len(order))
+		for j := range row {
+			row[j] = "."
+		}
+		row[i] = "-"
+		for _, imp := range g.edges[name] {
+			row[index[imp]] = "X"
+		}
+
+		fmt.Fprintf(w, "%*d %-*s ", numWidth, i+1, nameWidth, name)
+		for _, cell := range row {
+			fmt.Fprintf(w, " %*s", numWidth, cell)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"sort"
+)
+
+var (
+	checkDupes  = flag.Bool("check-dupes", false, "report import paths that differ but resolve to the same directory, through a symlink or a case-insensitive file system")
+	failOnDupes = flag.Bool("fail-on-dupes", false, "exit with a non-zero status if -check-dupes finds any")
+)
+
+// reportDirDupes reports every import path that resolved to the
+// directory of a package imported by another path. It returns the number
+// of such import paths.
+func reportDirDupes() int {
+	var dupes []string
+	for path := range dirDupes {
+		dupes = append(dupes, path)
+	}
+	sort.Strings(dupes)
+	for _, path := range dupes {
+		report("%s is the same package as %s, in %s\n", path, dirDupes[path], pkgs[dirDupes[path]].Dir)
+	}
+	return len(dupes)
+}
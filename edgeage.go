@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var edgeAge = flag.Bool("edge-age", false, "color edges by when their import was added according to git blame, from blue for the oldest to red for the newest")
+
+// importTimes returns, for each import of pkg by canonical path, the Unix
+// time its import spec was first committed according to git blame. Files
+// that git blame fails for are skipped.
+func importTimes(pkg *build.Package) (map[string]int64, error) {
+	times := make(map[string]int64)
+	fset := token.NewFileSet()
+	for _, name := range sourceFiles(pkg) {
+		file := filepath.Join(pkg.Dir, name)
+		f, err := parser.ParseFile(fset, file, nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", name, err)
+		}
+		if len(f.Imports) == 0 {
+			continue
+		}
+		lines, err := blameTimes(pkg.Dir, name)
+		if err != nil {
+			// Most likely the file isn't committed yet.
+			debugf("%s", err)
+			continue
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			imp := canonicalPath(path)
+			t, ok := lines[fset.Position(spec.Path.Pos()).Line]
+			if !ok {
+				continue
+			}
+			if old, ok := times[imp]; !ok || t < old {
+				times[imp] = t
+			}
+		}
+	}
+	return times, nil
+}
+
+// blameTimes runs git blame on the named file in dir and returns the
+// author time of each line.
+func blameTimes(dir, name string) (map[int]int64, error) {
+	cmd := exec.Command("git", "blame", "--porcelain", "--", name)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git blame %s failed: %s", filepath.Join(dir, name), err)
+	}
+
+	commitTimes := make(map[string]int64)
+	lines := make(map[int]int64)
+	var commit string
+	var line int
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		text := s.Text()
+		switch {
+		case strings.HasPrefix(text, "\t"):
+			// The contents of the line, ending its entry.
+			lines[line] = commitTimes[commit]
+		case strings.HasPrefix(text, "author-time "):
+			t, _ := strconv.ParseInt(strings.TrimPrefix(text, "author-time "), 10, 64)
+			commitTimes[commit] = t
+		default:
+			fields := strings.Fields(text)
+			if len(fields) >= 3 && len(fields[0]) == 40 {
+				commit = fields[0]
+				line, _ = strconv.Atoi(fields[2])
+			}
+		}
+	}
+	return lines, s.Err()
+}
+
+// markEdgeAge colors the edges of g by the time their import was added,
+// on a scale from blue for the oldest to red for the newest. Packages
+// that git blame fails for are left alone.
+func markEdgeAge(g *graph) {
+	times := make(map[edge]int64)
+	var oldest, newest int64
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		if pkg.Goroot || pkg.Dir == "" || len(g.edges[name]) == 0 {
+			continue
+		}
+		imports, err := importTimes(pkg)
+		if err != nil {
+			warnf("can't date the imports of %s: %s", name, err)
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			t, ok := imports[imp]
+			if !ok {
+				continue
+			}
+			times[edge{name, imp}] = t
+			if oldest == 0 || t < oldest {
+				oldest = t
+			}
+			if t > newest {
+				newest = t
+			}
+		}
+	}
+
+	if len(times) == 0 {
+		warnf("git blame found no imports to date; is the code in a git repository?")
+		return
+	}
+	for e, t := range times {
+		age := 1.0
+		if newest > oldest {
+			age = float64(newest-t) / float64(newest-oldest)
+		}
+		// Hue 0 is red, 0.66 is blue.
+		g.setEdgeAttr(e.from, e.to, "color", fmt.Sprintf("%.3f 1.000 0.900", 0.66*age))
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import "flag"
+
+var edgeSummary = flag.Bool("edge-summary", false, "print on stderr how many edges of the graph there are of each kind, such as first-party -> external")
+
+// edgeKinds lists the kinds of edges reported by -edge-summary, in the
+// order they are printed.
+var edgeKinds = []string{
+	"first-party -> first-party",
+	"first-party -> external",
+	"first-party -> stdlib",
+	"external -> external",
+	"external -> first-party",
+	"external -> stdlib",
+	"stdlib -> stdlib",
+	"test",
+}
+
+// edgeKind returns the kind of the import of to by from: "test" if only
+// tests import it, and otherwise the kinds of both packages.
+func edgeKind(isFirstParty func(string) bool, from, to string) string {
+	if isTestImport(pkgs[from], to) {
+		return "test"
+	}
+	return packageKind(isFirstParty, from) + " -> " + packageKind(isFirstParty, to)
+}
+
+// reportEdgeSummary prints the number of edges of g of each kind.
+func reportEdgeSummary(g *graph) {
+	isFirstParty := firstParty(g)
+	counts := make(map[string]int)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			counts[edgeKind(isFirstParty, name, imp)]++
+		}
+	}
+	for _, kind := range edgeKinds {
+		report("%5d %s\n", counts[kind], kind)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "flag"
+
+var (
+	maxFanout = flag.Int("max-fanout", 0, "highlight and report the packages that directly import more than this many packages in the graph")
+	failOnFat = flag.Bool("fail-on-fat", false, "exit with a non-zero status if any package exceeds -max-fanout")
+)
+
+// checkFanout colors the packages in g that import more than max packages
+// red, and lists them on stderr with their number of imports. It returns
+// the number of such packages.
+func checkFanout(g *graph, max int) int {
+	n := 0
+	for _, name := range g.nodes {
+		if len(g.edges[name]) <= max {
+			continue
+		}
+		n++
+		g.setNodeAttr(name, "color", "red")
+		report("%s imports %d packages\n", name, len(g.edges[name]))
+	}
+	return n
+}
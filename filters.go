@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+var (
+	infra         = flag.String("infra", "", "a comma-separated list of infrastructure packages whose incoming edges are hidden; each is labelled with its importer count instead")
+	externalOnly  = flag.Bool("external-only", false, "hide the imports between packages of the root's module, keeping only those that reach outside of it")
+	externalTouch = flag.Bool("external-touchpoints", false, "only render the packages of the root's module that import third-party packages directly, and those third-party packages")
+	edgesTo       = flag.String("edges-to", "", "only render the imports of packages matching this pattern, where ... matches anything, and the packages they connect")
+	directUnion   = flag.Bool("direct-union", false, "only render the packages of the root's module and the packages they import directly")
+	spine         = flag.String("spine", "", "a comma-separated list of packages; only render the packages on some path from the root to one of them")
+	prune         = flag.String("prune", "", "hide this package and every package that is only in the graph because of it")
+	commonDeps    = flag.Bool("common-deps", false, "given several packages, only render the packages that all of them depend on")
+	minFanin      = flag.Int("min-fanin", 0, "hide packages imported by fewer than this many packages in the graph, except the roots")
+	testsOnly     = flag.Bool("tests-only", false, "only render the imports that come from tests (implies -t)")
+)
+
+// hideInfraEdges removes every edge into the given packages, and labels
+// each of them with the number of edges hidden.
+func hideInfraEdges(g *graph, infra []string) {
+	isInfra := make(map[string]bool)
+	for _, name := range infra {
+		isInfra[name] = true
+	}
+
+	hidden := make(map[string]int)
+	g.filterEdges(func(from, to string) bool {
+		if isInfra[to] {
+			hidden[to]++
+			return false
+		}
+		return true
+	})
+	for _, name := range infra {
+		if g.hasNode(name) {
+			g.addNote(name, fmt.Sprintf("(%d importers hidden)", hidden[name]))
+		}
+	}
+}
+
+// keepTestEdges reduces g to the edges that only exist because of test
+// imports and the packages they connect. It returns the number of
+// packages that are imported by tests but by no regular package.
+func keepTestEdges(g *graph) int {
+	regular := make(map[string]bool)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			if !isTestImport(pkgs[name], imp) {
+				regular[imp] = true
+			}
+		}
+	}
+
+	g.filterEdges(func(from, to string) bool {
+		return isTestImport(pkgs[from], to)
+	})
+
+	keep := make(map[string]bool)
+	testOnly := make(map[string]bool)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			keep[name], keep[imp] = true, true
+			if !regular[imp] {
+				testOnly[imp] = true
+			}
+		}
+	}
+	g.keepNodes(keep)
+	return len(testOnly)
+}
+
+// dropLowFanin removes the packages of g, other than its roots, that are
+// imported by fewer than min packages. Fan-in is computed once, before
+// anything is removed.
+func dropLowFanin(g *graph, min int) {
+	fanin := g.fanin()
+	keep := make(map[string]bool)
+	for _, name := range g.nodes {
+		keep[name] = fanin[name] >= min
+	}
+	for _, name := range g.roots {
+		keep[name] = true
+	}
+	g.keepNodes(keep)
+}
+
+// keepCommonDeps reduces g to the packages that every root of g depends
+// on, directly or indirectly.
+func keepCommonDeps(g *graph) {
+	keep := make(map[string]bool)
+	for i, root := range g.roots {
+		deps := g.reachable(g.edges[root]...)
+		if i == 0 {
+			keep = deps
+			continue
+		}
+		for name := range keep {
+			if !deps[name] {
+				delete(keep, name)
+			}
+		}
+	}
+	g.keepNodes(keep)
+}
+
+// keepSpine reduces g to the packages that lie on some path from a root of
+// g to one of targets, including both ends.
+func keepSpine(g *graph, targets []string) {
+	forward := g.reachable(g.roots...)
+	g.transpose()
+	backward := g.reachable(targets...)
+	g.transpose()
+
+	keep := make(map[string]bool)
+	for name := range forward {
+		keep[name] = backward[name]
+	}
+	g.keepNodes(keep)
+}
+
+// prunePackage removes name from g along with every package that can no
+// longer be reached from a root of g without going through it.
+func prunePackage(g *graph, name string) {
+	g.filterEdges(func(from, to string) bool {
+		return to != name
+	})
+	keep := g.reachable(g.roots...)
+	delete(keep, name)
+	g.keepNodes(keep)
+}
+
+// keepExternalEdges removes the edges of g between two packages of the
+// root's module, and the packages of the module left without any import
+// from outside of it.
+func keepExternalEdges(g *graph) {
+	isFirstParty := firstParty(g)
+	g.filterEdges(func(from, to string) bool {
+		return !isFirstParty(from) || !isFirstParty(to)
+	})
+
+	keep := make(map[string]bool)
+	for _, name := range g.nodes {
+		keep[name] = !isFirstParty(name) || len(g.edges[name]) > 0
+	}
+	g.keepNodes(keep)
+}
+
+// keepExternalTouchpoints reduces g to the imports of third-party
+// packages, neither in the root's module nor in the standard library, by
+// packages of the root's module, and the packages at both ends.
+func keepExternalTouchpoints(g *graph) {
+	isFirstParty := firstParty(g)
+	g.filterEdges(func(from, to string) bool {
+		return isFirstParty(from) && !isFirstParty(to) && !pkgs[to].Goroot
+	})
+
+	keep := make(map[string]bool)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			keep[name], keep[imp] = true, true
+		}
+	}
+	g.keepNodes(keep)
+}
+
+// keepEdgesTo reduces g to the edges into packages matching pattern and
+// the packages at either end of them.
+func keepEdgesTo(g *graph, pattern string) {
+	match := matchPattern(pattern)
+	g.filterEdges(func(from, to string) bool {
+		return match(to)
+	})
+
+	keep := make(map[string]bool)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			keep[name], keep[imp] = true, true
+		}
+	}
+	g.keepNodes(keep)
+}
+
+// keepDirectUnion reduces g to the packages of the root's module and the
+// packages they import directly, hiding everything only imported
+// transitively.
+func keepDirectUnion(g *graph) {
+	isFirstParty := firstParty(g)
+	keep := make(map[string]bool)
+	for _, name := range g.nodes {
+		if !isFirstParty(name) {
+			continue
+		}
+		keep[name] = true
+		for _, imp := range g.edges[name] {
+			keep[imp] = true
+		}
+	}
+	g.keepNodes(keep)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"go/build"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var skipGenerated = flag.Bool("skip-generated", false, "ignore packages whose non-test Go files are all generated")
+
+// generatedRE matches the comment marking a generated Go file, as
+// described at https://golang.org/s/generatedcode.
+var generatedRE = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// isGenerated reports whether every non-test Go file of pkg is generated.
+func isGenerated(pkg *build.Package) bool {
+	files := append(append([]string{}, pkg.GoFiles...), pkg.CgoFiles...)
+	if len(files) == 0 {
+		return false
+	}
+	for _, name := range files {
+		if !isGeneratedFile(filepath.Join(pkg.Dir, name)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isGeneratedFile reports whether the Go file at path carries the
+// generated code marker before its package clause.
+func isGeneratedFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if generatedRE.MatchString(line) {
+			return true
+		}
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+	}
+	return false
+}
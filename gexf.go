@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// gexfNodeAttrs lists the attributes written for every node in GEXF
+// output, by id, with their type.
+var gexfNodeAttrs = []struct{ title, typ string }{
+	{"module", "string"},
+	{"goroot", "boolean"},
+	{"cgo", "boolean"},
+	{"fanin", "integer"},
+	{"fanout", "integer"},
+}
+
+// writeGEXF writes the graph as a GEXF 1.3 document, the native format of
+// Gephi, with the module, the fan-in and fan-out and whether it is part
+// of the standard library or uses cgo as attributes of each package.
+func writeGEXF(w io.Writer, g *graph) error {
+	fmt.Fprintln(w, xml.Header+`<gexf xmlns="http://gexf.net/1.3" version="1.3">`)
+	fmt.Fprintln(w, `<graph mode="static" defaultedgetype="directed">`)
+	fmt.Fprintln(w, `<attributes class="node">`)
+	for i, a := range gexfNodeAttrs {
+		fmt.Fprintf(w, "<attribute id=\"%d\" title=\"%s\" type=\"%s\"/>\n", i, a.title, a.typ)
+	}
+	fmt.Fprintln(w, "</attributes>")
+
+	fanin := g.fanin()
+	fmt.Fprintln(w, "<nodes>")
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		values := []string{
+			moduleOf(pkg),
+			strconv.FormatBool(pkg.Goroot),
+			strconv.FormatBool(len(pkg.CgoFiles) > 0),
+			strconv.Itoa(fanin[name]),
+			strconv.Itoa(len(g.edges[name])),
+		}
+		fmt.Fprintf(w, "<node id=\"%d\" label=\"%s\"><attvalues>", getId(name), xmlEscape(name))
+		for i, v := range values {
+			fmt.Fprintf(w, "<attvalue for=\"%d\" value=\"%s\"/>", i, xmlEscape(v))
+		}
+		fmt.Fprintln(w, "</attvalues></node>")
+	}
+	fmt.Fprintln(w, "</nodes>")
+
+	fmt.Fprintln(w, "<edges>")
+	n := 0
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			fmt.Fprintf(w, "<edge id=\"%d\" source=\"%d\" target=\"%d\"/>\n", n, getId(name), getId(imp))
+			n++
+		}
+	}
+	fmt.Fprintln(w, "</edges>")
+	fmt.Fprintln(w, "</graph>")
+	_, err := fmt.Fprintln(w, "</gexf>")
+	return err
+}
+
+// xmlEscape returns s escaped for use in XML text and attribute values.
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+var (
+	assertFile = flag.String("assert", "", "compare the graph with the one saved in this JSON file, report the differences and exit with a non-zero status if there are any")
+	update     = flag.Bool("update", false, "with -assert, write the current graph to the file instead of comparing")
+	graphHash  = flag.Bool("graph-hash", false, "print a SHA-256 hash of the packages, their modules and the imports of the graph instead of the graph, to tell whether it changed")
+)
+
+// goldenGraph is the canonical form of a graph saved by -assert -update:
+// the sorted packages, and the sorted imports of each package that has
+// any.
+type goldenGraph struct {
+	Packages []string            `json:"packages"`
+	Imports  map[string][]string `json:"imports"`
+}
+
+func newGoldenGraph(g *graph) goldenGraph {
+	golden := goldenGraph{
+		Packages: append([]string{}, g.nodes...),
+		Imports:  make(map[string][]string),
+	}
+	for _, name := range g.nodes {
+		if len(g.edges[name]) == 0 {
+			continue
+		}
+		imports := append([]string{}, g.edges[name]...)
+		sort.Strings(imports)
+		golden.Imports[name] = imports
+	}
+	return golden
+}
+
+// writeGolden saves the canonical form of g to path.
+func writeGolden(path string, g *graph) error {
+	data, err := json.MarshalIndent(newGoldenGraph(g), "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// assertGolden compares g with the graph saved in path and reports each
+// package and import found in only one of them. It returns the
+// number of differences.
+func assertGolden(path string, g *graph) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var want goldenGraph
+	if err := json.Unmarshal(data, &want); err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+	got := newGoldenGraph(g)
+
+	diffs := 0
+	diff := func(want, got []string, format string) {
+		has := make(map[string]bool)
+		for _, s := range got {
+			has[s] = true
+		}
+		for _, s := range want {
+			if !has[s] {
+				report("- "+format+"\n", s)
+				diffs++
+			}
+			delete(has, s)
+		}
+		for _, s := range got {
+			if has[s] {
+				report("+ "+format+"\n", s)
+				diffs++
+			}
+		}
+	}
+	diff(want.Packages, got.Packages, "%s")
+	diff(goldenEdges(want), goldenEdges(got), "%s")
+	return diffs, nil
+}
+
+// goldenEdges returns the imports of golden as sorted "from -> to" lines.
+func goldenEdges(golden goldenGraph) []string {
+	var edges []string
+	for from, imports := range golden.Imports {
+		for _, to := range imports {
+			edges = append(edges, from+" -> "+to)
+		}
+	}
+	sort.Strings(edges)
+	return edges
+}
+
+// writeGraphHash writes the hex SHA-256 hash of the canonical form of g:
+// its sorted packages with their modules, followed by its sorted imports.
+// It only depends on the graph, not on where or how often it is built.
+func writeGraphHash(w io.Writer, g *graph) error {
+	h := sha256.New()
+	golden := newGoldenGraph(g)
+	for _, name := range golden.Packages {
+		fmt.Fprintf(h, "%s %s\n", name, moduleOf(pkgs[name]))
+	}
+	for _, e := range goldenEdges(golden) {
+		fmt.Fprintln(h, e)
+	}
+	_, err := fmt.Fprintf(w, "%x\n", h.Sum(nil))
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+var checkGoModFlag = flag.Bool("check-gomod", false, "report modules required by go.mod that no package in the graph uses, and used modules go.mod doesn't require")
+
+// checkGoMod compares the modules providing the packages in g with the
+// requirements in the go.mod of the root package's module, and reports
+// each difference.
+func checkGoMod(g *graph) error {
+	if len(g.roots) == 0 {
+		return nil
+	}
+	modFile := findGoMod(pkgs[g.roots[0]].Dir)
+	if modFile == "" {
+		return fmt.Errorf("%s is not in a module", g.roots[0])
+	}
+	data, err := os.ReadFile(modFile)
+	if err != nil {
+		return err
+	}
+	main := modulePath(data)
+
+	used := make(map[string]bool)
+	for _, name := range g.nodes {
+		if mod := moduleOf(pkgs[name]); mod != "" && mod != main && mod != gopathModule {
+			used[mod] = true
+		}
+	}
+
+	required := make(map[string]bool)
+	for _, req := range parseRequires(data) {
+		required[req.path] = true
+		if used[req.path] {
+			continue
+		}
+		if req.indirect {
+			report("%s requires %s (indirect), but no package in the graph uses it\n", modFile, req.path)
+		} else {
+			report("%s requires %s, but no package in the graph uses it\n", modFile, req.path)
+		}
+	}
+
+	var missing []string
+	for mod := range used {
+		if !required[mod] {
+			missing = append(missing, mod)
+		}
+	}
+	sort.Strings(missing)
+	for _, mod := range missing {
+		report("packages in the graph use %s, but %s doesn't require it\n", mod, modFile)
+	}
+	return nil
+}
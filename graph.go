@@ -0,0 +1,484 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// A graph is the view of the collected packages that gets rendered: the
+// packages that survived filtering and the imports between them.
+type graph struct {
+	// nodes holds the import paths of the packages in the graph, sorted.
+	nodes []string
+	// roots holds the nodes that the graph was built from.
+	roots []string
+	// edges maps each node to the nodes it imports, in import order.
+	edges map[string][]string
+
+	// nodeAttrs and edgeAttrs hold DOT attributes that override the
+	// defaults for individual nodes and edges.
+	nodeAttrs map[string]attrs
+	edgeAttrs map[edge]attrs
+
+	// notes holds annotations appended to the labels of nodes.
+	notes map[string][]string
+
+	// comments holds remarks about the graph as a whole, written as
+	// comments in formats that support them.
+	comments []string
+
+	// ranks, if set, lists groups of nodes that are laid out on the same
+	// rank, in order from the top of the graph down.
+	ranks [][]string
+
+	// clusters, if set, lists groups of nodes that are drawn together in
+	// a labelled box.
+	clusters []cluster
+}
+
+// A cluster is a labelled group of nodes, possibly holding nested
+// clusters.
+type cluster struct {
+	label    string
+	nodes    []string
+	clusters []cluster
+}
+
+// An edge is an import of one package by another.
+type edge struct {
+	from, to string
+}
+
+// emptyGraph returns a graph without any nodes.
+func emptyGraph() *graph {
+	return &graph{
+		edges:     make(map[string][]string),
+		nodeAttrs: make(map[string]attrs),
+		edgeAttrs: make(map[edge]attrs),
+		notes:     make(map[string][]string),
+	}
+}
+
+// newGraph builds the graph from the packages gathered by processPackage,
+// dropping ignored packages and any imports of packages in Goroot unless
+// -d is given.
+func newGraph() *graph {
+	g := emptyGraph()
+	for name, pkg := range pkgs {
+		if !isIgnored(pkg) {
+			g.nodes = append(g.nodes, name)
+		}
+	}
+	sort.Strings(g.nodes)
+
+	for _, name := range roots {
+		if pkg := pkgs[name]; pkg != nil && !isIgnored(pkg) {
+			g.roots = append(g.roots, name)
+		}
+	}
+
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+
+		// Don't render imports from packages in Goroot
+		if pkg.Goroot && !*delveGoroot {
+			continue
+		}
+
+		for _, imp := range getImports(pkg) {
+			impPkg := pkgs[imp]
+			if impPkg == nil || isIgnored(impPkg) {
+				continue
+			}
+			g.edges[name] = append(g.edges[name], imp)
+		}
+	}
+	return g
+}
+
+// setNodeAttr sets a DOT attribute on the node name.
+func (g *graph) setNodeAttr(name, key, value string) {
+	g.nodeAttrs[name] = g.nodeAttrs[name].set(key, value)
+}
+
+// addNote appends an annotation to the label of the node name.
+func (g *graph) addNote(name, note string) {
+	g.notes[name] = append(g.notes[name], note)
+}
+
+// setEdgeAttr sets a DOT attribute on the edge from -> to.
+func (g *graph) setEdgeAttr(from, to, key, value string) {
+	e := edge{from, to}
+	g.edgeAttrs[e] = g.edgeAttrs[e].set(key, value)
+}
+
+// fanin returns the number of importers of each node.
+func (g *graph) fanin() map[string]int {
+	in := make(map[string]int)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			in[imp]++
+		}
+	}
+	return in
+}
+
+// numEdges returns the total number of edges in the graph.
+func (g *graph) numEdges() int {
+	n := 0
+	for _, imps := range g.edges {
+		n += len(imps)
+	}
+	return n
+}
+
+// filterEdges removes every edge for which keep returns false.
+func (g *graph) filterEdges(keep func(from, to string) bool) {
+	for _, name := range g.nodes {
+		var kept []string
+		for _, imp := range g.edges[name] {
+			if keep(name, imp) {
+				kept = append(kept, imp)
+			}
+		}
+		g.edges[name] = kept
+	}
+}
+
+// dedupNested removes edges made redundant by nesting: when a package
+// imports both a package and one of its descendants, the edge to the
+// descendant is hidden if mode is "descendant", or the edge to the parent
+// if mode is "parent".
+func (g *graph) dedupNested(mode string) {
+	g.filterEdges(func(from, to string) bool {
+		for _, other := range g.edges[from] {
+			switch mode {
+			case "descendant":
+				if strings.HasPrefix(to, other+"/") {
+					return false
+				}
+			case "parent":
+				if strings.HasPrefix(other, to+"/") {
+					return false
+				}
+			}
+		}
+		return true
+	})
+}
+
+// reachable returns the set of nodes reachable from the given nodes,
+// including the nodes themselves.
+func (g *graph) reachable(from ...string) map[string]bool {
+	seen := make(map[string]bool)
+	stack := append([]string{}, from...)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		stack = append(stack, g.edges[name]...)
+	}
+	return seen
+}
+
+// shortestPath returns the shortest chain of imports leading from any of
+// the packages in from to the package to, including both ends, or nil if
+// to is not reachable.
+func (g *graph) shortestPath(to string, from ...string) []string {
+	prev := make(map[string]string)
+	seen := make(map[string]bool)
+	queue := append([]string{}, from...)
+	for _, name := range from {
+		seen[name] = true
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if name == to {
+			path := []string{name}
+			for name != "" {
+				name = prev[name]
+				if name != "" {
+					path = append([]string{name}, path...)
+				}
+			}
+			return path
+		}
+		for _, imp := range g.edges[name] {
+			if !seen[imp] {
+				seen[imp] = true
+				prev[imp] = name
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return nil
+}
+
+// hasNode reports whether name is a node of g.
+func (g *graph) hasNode(name string) bool {
+	i := sort.SearchStrings(g.nodes, name)
+	return i < len(g.nodes) && g.nodes[i] == name
+}
+
+// keepNodes removes every node not in keep, along with its edges.
+func (g *graph) keepNodes(keep map[string]bool) {
+	var nodes []string
+	for _, name := range g.nodes {
+		if keep[name] {
+			nodes = append(nodes, name)
+		} else {
+			delete(g.edges, name)
+		}
+	}
+	g.nodes = nodes
+
+	g.roots = keepNames(g.roots, keep)
+
+	for i, rank := range g.ranks {
+		g.ranks[i] = keepNames(rank, keep)
+	}
+	g.clusters = keepClusters(g.clusters, keep)
+
+	g.filterEdges(func(from, to string) bool {
+		return keep[to]
+	})
+}
+
+// keepNames returns the names in keep, in order.
+func keepNames(names []string, keep map[string]bool) []string {
+	var kept []string
+	for _, name := range names {
+		if keep[name] {
+			kept = append(kept, name)
+		}
+	}
+	return kept
+}
+
+// keepClusters returns clusters with their nodes reduced to those in keep,
+// dropping the clusters left empty.
+func keepClusters(clusters []cluster, keep map[string]bool) []cluster {
+	var kept []cluster
+	for _, c := range clusters {
+		c.nodes = keepNames(c.nodes, keep)
+		c.clusters = keepClusters(c.clusters, keep)
+		if len(c.nodes) > 0 || len(c.clusters) > 0 {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// collapse merges every group of nodes that group maps to the same
+// non-empty key into a single node named by that key, redirecting their
+// edges and dropping the edges within the group. It returns the number
+// of nodes folded into each key.
+func (g *graph) collapse(group func(string) string) map[string]int {
+	target := func(name string) string {
+		if key := group(name); key != "" {
+			return key
+		}
+		return name
+	}
+
+	counts := make(map[string]int)
+	seen := make(map[string]bool)
+	var nodes []string
+	for _, name := range g.nodes {
+		t := target(name)
+		if t != name {
+			counts[t]++
+		}
+		if !seen[t] {
+			seen[t] = true
+			nodes = append(nodes, t)
+		}
+	}
+	sort.Strings(nodes)
+
+	edges := make(map[string][]string)
+	found := make(map[edge]bool)
+	for _, name := range g.nodes {
+		from := target(name)
+		for _, imp := range g.edges[name] {
+			to := target(imp)
+			e := edge{from, to}
+			if from == to || found[e] {
+				continue
+			}
+			found[e] = true
+			edges[from] = append(edges[from], to)
+		}
+	}
+
+	var roots []string
+	for _, name := range g.roots {
+		roots = append(roots, target(name))
+	}
+
+	g.nodes, g.edges, g.roots = nodes, edges, roots
+	return counts
+}
+
+// topoOrder returns the nodes of g ordered so that importers come before
+// the packages they import. Cycles are broken at the edge that closes
+// them in a depth-first walk from the roots.
+func (g *graph) topoOrder() []string {
+	var order []string
+	visited := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, imp := range g.edges[name] {
+			visit(imp)
+		}
+		order = append(order, name)
+	}
+	for _, name := range g.roots {
+		visit(name)
+	}
+	for _, name := range g.nodes {
+		visit(name)
+	}
+
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// cycles returns the strongly connected components of g with more than
+// one node, each sorted, in the order of their smallest node.
+func (g *graph) cycles() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var sccs [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+		for _, imp := range g.edges[name] {
+			if _, ok := index[imp]; !ok {
+				visit(imp)
+				if low[imp] < low[name] {
+					low[name] = low[imp]
+				}
+			} else if onStack[imp] && index[imp] < low[name] {
+				low[name] = index[imp]
+			}
+		}
+		if low[name] != index[name] {
+			return
+		}
+		var scc []string
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			scc = append(scc, n)
+			if n == name {
+				break
+			}
+		}
+		if len(scc) > 1 {
+			sort.Strings(scc)
+			sccs = append(sccs, scc)
+		}
+	}
+	for _, name := range g.nodes {
+		if _, ok := index[name]; !ok {
+			visit(name)
+		}
+	}
+
+	sort.Slice(sccs, func(i, j int) bool { return sccs[i][0] < sccs[j][0] })
+	return sccs
+}
+
+// rename renames the nodes of g that are keys of names to the
+// corresponding values, carrying over their edges, attributes and notes.
+func (g *graph) rename(names map[string]string) {
+	to := func(name string) string {
+		if n, ok := names[name]; ok {
+			return n
+		}
+		return name
+	}
+
+	edges := make(map[string][]string)
+	for i, name := range g.nodes {
+		var imps []string
+		for _, imp := range g.edges[name] {
+			imps = append(imps, to(imp))
+		}
+		edges[to(name)] = imps
+		g.nodes[i] = to(name)
+	}
+	sort.Strings(g.nodes)
+	g.edges = edges
+
+	for i, name := range g.roots {
+		g.roots[i] = to(name)
+	}
+	for _, rank := range g.ranks {
+		for i, name := range rank {
+			rank[i] = to(name)
+		}
+	}
+	renameClusters(g.clusters, to)
+
+	nodeAttrs := make(map[string]attrs)
+	for name, a := range g.nodeAttrs {
+		nodeAttrs[to(name)] = a
+	}
+	g.nodeAttrs = nodeAttrs
+	edgeAttrs := make(map[edge]attrs)
+	for e, a := range g.edgeAttrs {
+		edgeAttrs[edge{to(e.from), to(e.to)}] = a
+	}
+	g.edgeAttrs = edgeAttrs
+	notes := make(map[string][]string)
+	for name, n := range g.notes {
+		notes[to(name)] = n
+	}
+	g.notes = notes
+}
+
+// transpose reverses the direction of every edge of g, carrying edge
+// attributes over to the reversed edges.
+func (g *graph) transpose() {
+	edges := make(map[string][]string)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			edges[imp] = append(edges[imp], name)
+		}
+	}
+	g.edges = edges
+
+	edgeAttrs := make(map[edge]attrs)
+	for e, a := range g.edgeAttrs {
+		edgeAttrs[edge{e.to, e.from}] = a
+	}
+	g.edgeAttrs = edgeAttrs
+}
+
+func renameClusters(clusters []cluster, to func(string) string) {
+	for _, c := range clusters {
+		for i, name := range c.nodes {
+			c.nodes[i] = to(name)
+		}
+		renameClusters(c.clusters, to)
+	}
+}
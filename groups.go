@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var groupsFile = flag.String("groups", "", "a file mapping import path patterns to group names, one per line; each group is drawn as a cluster")
+
+// defaultGroup is the cluster that packages matching none of the -groups
+// patterns are drawn in.
+const defaultGroup = "other"
+
+// A groupRule assigns the packages matching a pattern to a group.
+type groupRule struct {
+	pattern string
+	match   func(string) bool
+	group   string
+}
+
+// readGroups reads a groups file. Each line holds an import path pattern,
+// where "..." matches any string, followed by the name of the group the
+// packages matching it belong to.
+func readGroups(file string) ([]groupRule, error) {
+	lines, err := readList(file)
+	if err != nil {
+		return nil, err
+	}
+	var rules []groupRule
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s: invalid line %q", file, line)
+		}
+		rules = append(rules, groupRule{
+			pattern: fields[0],
+			match:   matchPattern(fields[0]),
+			group:   strings.Join(fields[1:], " "),
+		})
+	}
+	return rules, nil
+}
+
+// clusterGroups draws the packages of g in one cluster per group, putting
+// each package in the group of the first rule matching it, or in
+// defaultGroup. Rules that match no package, or only packages taken by
+// earlier rules, are reported.
+func clusterGroups(g *graph, rules []groupRule) {
+	var order []string
+	members := make(map[string][]string)
+	// A rule can match packages and still not get any of them, when
+	// earlier rules claim them all.
+	matched := make([]bool, len(rules))
+	used := make([]bool, len(rules))
+	for _, name := range g.nodes {
+		group := defaultGroup
+		claimed := false
+		for i, r := range rules {
+			if !r.match(name) {
+				continue
+			}
+			matched[i] = true
+			if !claimed {
+				group = r.group
+				used[i] = true
+				claimed = true
+			}
+		}
+		if members[group] == nil {
+			order = append(order, group)
+		}
+		members[group] = append(members[group], name)
+	}
+	for i, r := range rules {
+		switch {
+		case !matched[i]:
+			warnf("%s in %s matches no package", r.pattern, *groupsFile)
+		case !used[i]:
+			warnf("%s in %s only matches packages of earlier rules", r.pattern, *groupsFile)
+		}
+	}
+	for _, group := range order {
+		g.clusters = append(g.clusters, cluster{label: group, nodes: members[group]})
+	}
+}
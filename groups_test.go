@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClusterGroups(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"groups": "ex/app/billing/... billing\nex/app/... platform\nex/app/billing shadowed\nex/none/... nobody\n",
+	})
+	rules, err := readGroups(filepath.Join(dir, "groups"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setFlag(t, groupsFile, "groups")
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	g := emptyGraph()
+	g.nodes = []string{"ex/app", "ex/app/billing", "ex/lib"}
+	clusterGroups(g, rules)
+
+	want := []cluster{
+		{label: "platform", nodes: []string{"ex/app"}},
+		{label: "billing", nodes: []string{"ex/app/billing"}},
+		{label: defaultGroup, nodes: []string{"ex/lib"}},
+	}
+	if !reflect.DeepEqual(g.clusters, want) {
+		t.Errorf("clusters = %v, want %v", g.clusters, want)
+	}
+	for _, warning := range []string{
+		"ex/app/billing in groups only matches packages of earlier rules",
+		"ex/none/... in groups matches no package",
+	} {
+		if !strings.Contains(logged.String(), warning) {
+			t.Errorf("no warning %q in:\n%s", warning, logged.String())
+		}
+	}
+	if n := strings.Count(logged.String(), "\n"); n != 2 {
+		t.Errorf("%d warnings, want 2:\n%s", n, logged.String())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var checkInternalFlag = flag.Bool("check-internal", false, "report and draw red the imports of internal packages that Go's visibility rules forbid, or that cross into another module")
+
+// internalParent returns the path that packages must be under to import
+// path according to Go's rules for internal packages, and false if path
+// has no internal element. For "a/b/internal/c" that is "a/b", and "" for
+// the standard library's "internal/c".
+func internalParent(path string) (string, bool) {
+	switch {
+	case strings.HasPrefix(path, "internal/") || path == "internal":
+		return "", true
+	case strings.HasSuffix(path, "/internal"):
+		return strings.TrimSuffix(path, "/internal"), true
+	}
+	if i := strings.LastIndex(path, "/internal/"); i >= 0 {
+		return path[:i], true
+	}
+	return "", false
+}
+
+// checkInternal colors every edge of g importing an internal package red
+// and reports it if the importer is not allowed to import it, or if
+// it is allowed but the two packages belong to different modules. It
+// returns the number of such edges.
+func checkInternal(g *graph) int {
+	n := 0
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			parent, ok := internalParent(imp)
+			if !ok {
+				continue
+			}
+			var problem string
+			switch {
+			case parent == "" && !pkgs[name].Goroot:
+				problem = "imports internal package of the standard library"
+			case parent != "" && name != parent && !strings.HasPrefix(name, parent+"/"):
+				problem = "imports internal package not under " + parent
+			case !pkgs[imp].Goroot && moduleOf(pkgs[name]) != moduleOf(pkgs[imp]):
+				problem = "imports internal package of module " + moduleOf(pkgs[imp])
+			default:
+				continue
+			}
+			n++
+			report("%s %s: %s\n", name, problem, imp)
+			g.setEdgeAttr(name, imp, "color", "red")
+		}
+	}
+	return n
+}
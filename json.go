@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	dumpPackages = flag.Bool("dump-packages", false, "write the go/build data of every package found as JSON instead of a graph")
+	hashes       = flag.Bool("hashes", false, "include a SHA-256 hash of the source files of each package in JSON output, to tell which packages changed")
+	fileMap      = flag.String("filemap", "", "also write a JSON file mapping each package in the graph to its directory and Go files")
+	jsonDir      = flag.String("json-dir", "", "also write a JSON file per package in the graph, with its imports and importers, into this directory")
+)
+
+// jsonNode is the JSON representation of a package in the graph.
+type jsonNode struct {
+	Type   string `json:"type,omitempty"`
+	ID     int    `json:"id"`
+	Path   string `json:"path"`
+	Goroot bool   `json:"goroot"`
+	Cgo    bool   `json:"cgo"`
+	Module string `json:"module,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+}
+
+// jsonEdge is the JSON representation of an import in the graph.
+type jsonEdge struct {
+	Type string `json:"type,omitempty"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+func newJSONNode(name string) jsonNode {
+	pkg := pkgs[name]
+	n := jsonNode{
+		ID:     getId(name),
+		Path:   name,
+		Goroot: pkg.Goroot,
+		Cgo:    len(pkg.CgoFiles) > 0,
+		Module: moduleOf(pkg),
+	}
+	if *hashes {
+		hash, err := sourceHash(pkg)
+		if err != nil {
+			warnf("failed to hash %s: %s", name, err)
+		}
+		n.Hash = hash
+	}
+	return n
+}
+
+// sourceHash returns the hex SHA-256 hash of the names and contents of
+// the source files of pkg, in sorted order, so that it only changes when
+// the source does.
+func sourceHash(pkg *build.Package) (string, error) {
+	files := sourceFiles(pkg)
+	sort.Strings(files)
+	h := sha256.New()
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeNDJSON writes the graph as newline-delimited JSON: one object per
+// node followed by one object per edge, each tagged with its type.
+func writeNDJSON(w io.Writer, g *graph) error {
+	enc := json.NewEncoder(w)
+	for _, name := range g.nodes {
+		n := newJSONNode(name)
+		n.Type = "node"
+		if err := enc.Encode(n); err != nil {
+			return err
+		}
+	}
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			if err := enc.Encode(jsonEdge{Type: "edge", From: name, To: imp}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// jsonPackage holds the parts of a build.Package written by -dump-packages.
+type jsonPackage struct {
+	ImportPath   string
+	Dir          string
+	Goroot       bool
+	GoFiles      []string
+	CgoFiles     []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+}
+
+// writePackages writes every package collected by processPackage, before
+// any filtering, as a JSON array sorted by import path.
+func writePackages(w io.Writer) error {
+	var names []string
+	for name := range pkgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dump := make([]jsonPackage, len(names))
+	for i, name := range names {
+		pkg := pkgs[name]
+		dump[i] = jsonPackage{
+			ImportPath:   pkg.ImportPath,
+			Dir:          pkg.Dir,
+			Goroot:       pkg.Goroot,
+			GoFiles:      pkg.GoFiles,
+			CgoFiles:     pkg.CgoFiles,
+			Imports:      pkg.Imports,
+			TestImports:  pkg.TestImports,
+			XTestImports: pkg.XTestImports,
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(dump)
+}
+
+// fileMapEntry is the location on disk of a package, as written by
+// -filemap.
+type fileMapEntry struct {
+	Dir   string   `json:"dir"`
+	Files []string `json:"files"`
+}
+
+// writeFileMap writes a JSON object mapping the import path of each node
+// in g to its directory and Go files.
+func writeFileMap(file string, g *graph) error {
+	m := make(map[string]fileMapEntry)
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		m[name] = fileMapEntry{Dir: pkg.Dir, Files: sourceFiles(pkg)}
+	}
+
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
+
+// jsonPackageFile is the contents of a file written by -json-dir.
+type jsonPackageFile struct {
+	jsonNode
+	Fanin     int      `json:"fanin"`
+	Fanout    int      `json:"fanout"`
+	Imports   []string `json:"imports"`
+	Importers []string `json:"importers"`
+}
+
+// writeJSONDir writes a JSON file for every node of g into dir, named by
+// jsonFileName.
+func writeJSONDir(dir string, g *graph) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	importers := make(map[string][]string)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			importers[imp] = append(importers[imp], name)
+		}
+	}
+
+	for _, name := range g.nodes {
+		f := jsonPackageFile{
+			jsonNode:  newJSONNode(name),
+			Fanin:     len(importers[name]),
+			Fanout:    len(g.edges[name]),
+			Imports:   append([]string{}, g.edges[name]...),
+			Importers: append([]string{}, importers[name]...),
+		}
+		data, err := json.MarshalIndent(f, "", "\t")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, jsonFileName(name)), append(data, '\n'), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonFileName returns the name of the -json-dir file for the package
+// name. Bytes other than letters, digits, dots and dashes are escaped as
+// _XX, so that distinct import paths never share a file.
+func jsonFileName(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '-':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "_%02x", c)
+		}
+	}
+	return b.String() + ".json"
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+var (
+	labelTemplate = flag.String("label-template", "", "a text/template for node labels, with fields ImportPath, Base, Module, Fanin, Fanout and Goroot")
+	wrapWidth     = flag.Int("wrap", 0, "break node labels into lines of about this many characters, between import path elements")
+	shortNames    = flag.Bool("short-names", false, "label nodes by the last element of their import path, lengthened only as far as needed where that collides")
+)
+
+// labelTmpl is the parsed -label-template, if one was given.
+var labelTmpl *template.Template
+
+// labelData is what -label-template is evaluated against.
+type labelData struct {
+	ImportPath string
+	Base       string
+	Module     string
+	Fanin      int
+	Fanout     int
+	Goroot     bool
+}
+
+// parseLabelTemplate parses -label-template so that mistakes are caught
+// before any work is done.
+func parseLabelTemplate() error {
+	if *labelTemplate == "" {
+		return nil
+	}
+	t, err := template.New("label").Parse(*labelTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid -label-template: %s", err)
+	}
+	labelTmpl = t
+	return nil
+}
+
+// nodeLabels returns the labels for the nodes of g that aren't labelled
+// by their import path, as selected by -label-template, -base-labels or
+// -short-names.
+func nodeLabels(g *graph) (map[string]string, error) {
+	switch {
+	case labelTmpl != nil:
+		fanin := g.fanin()
+		labels := make(map[string]string)
+		for _, name := range g.nodes {
+			pkg := pkgs[name]
+			data := labelData{
+				ImportPath: name,
+				Base:       path.Base(name),
+				Module:     moduleOf(pkg),
+				Fanin:      fanin[name],
+				Fanout:     len(g.edges[name]),
+				Goroot:     pkg.Goroot,
+			}
+			var buf bytes.Buffer
+			if err := labelTmpl.Execute(&buf, data); err != nil {
+				return nil, fmt.Errorf("failed to label %s: %s", name, err)
+			}
+			labels[name] = buf.String()
+		}
+		return labels, nil
+	case *baseLabelsFlag:
+		return baseLabels(g.nodes), nil
+	case *shortNames:
+		return shortLabels(g.nodes), nil
+	}
+	return nil, nil
+}
+
+// wrapLabel breaks label into lines of at most width characters, after
+// one of its slashes, as DOT line breaks. An element longer than width
+// gets a line of its own.
+func wrapLabel(label string, width int) string {
+	var lines []string
+	line := ""
+	for _, elem := range strings.SplitAfter(label, "/") {
+		if line != "" && len(line)+len(elem) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		line += elem
+	}
+	lines = append(lines, line)
+	return strings.Join(lines, `\n`)
+}
+
+// baseLabels returns labels for the given import paths made of their last
+// path element. When several paths share a last element, the label of
+// each is extended with the shortest run of parent elements that tells it
+// apart from the others, as in "config (service/a)".
+func baseLabels(names []string) map[string]string {
+	byBase := make(map[string][]string)
+	for _, name := range names {
+		base := path.Base(name)
+		byBase[base] = append(byBase[base], name)
+	}
+
+	labels := make(map[string]string)
+	for base, group := range byBase {
+		if len(group) == 1 {
+			labels[group[0]] = base
+			continue
+		}
+		for name, suffix := range uniqueSuffixes(group) {
+			if parent := path.Dir(suffix); parent != "." {
+				labels[name] = base + " (" + parent + ")"
+			} else {
+				labels[name] = base
+			}
+		}
+	}
+	return labels
+}
+
+// shortLabels returns labels for the given import paths made of their last
+// path element, or of as many trailing elements as it takes to tell them
+// apart from any other package collected, as in "a/config". Collisions
+// are looked for among all of pkgs, so that labels don't change when
+// filtering hides one side of a collision.
+func shortLabels(names []string) map[string]string {
+	byBase := make(map[string][]string)
+	for name := range pkgs {
+		base := path.Base(name)
+		byBase[base] = append(byBase[base], name)
+	}
+
+	labels := make(map[string]string)
+	suffixes := make(map[string]map[string]string)
+	for _, name := range names {
+		base := path.Base(name)
+		group := byBase[base]
+		if len(group) <= 1 {
+			labels[name] = base
+			continue
+		}
+		if suffixes[base] == nil {
+			suffixes[base] = uniqueSuffixes(group)
+		}
+		labels[name] = suffixes[base][name]
+	}
+	return labels
+}
+
+// uniqueSuffixes returns, for each of the given import paths, the shortest
+// trailing run of path elements that no other path in the set ends with.
+// Paths that are a suffix of another path map to themselves.
+func uniqueSuffixes(names []string) map[string]string {
+	suffixes := make(map[string]string)
+	for _, name := range names {
+		elems := strings.Split(name, "/")
+		for k := 1; k <= len(elems); k++ {
+			suffix := strings.Join(elems[len(elems)-k:], "/")
+			unique := true
+			for _, other := range names {
+				if other != name && (other == suffix || strings.HasSuffix(other, "/"+suffix)) {
+					unique = false
+					break
+				}
+			}
+			if unique || k == len(elems) {
+				suffixes[name] = suffix
+				break
+			}
+		}
+	}
+	return suffixes
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var (
+	layersFlag     = flag.String("layers", "", "a comma-separated list of prefixes, from the top layer down, to rank packages by; upward imports are drawn red")
+	violationsOnly = flag.Bool("violations-only", false, "with -layers, only render the imports going up the layers and the packages they connect")
+	rankByLevel    = flag.Bool("rank-by-level", false, "rank packages by their depth, the longest chain of imports below them, so that packages at the same depth line up")
+	maxLayerSkip   = flag.Int("max-layer-skip", 0, "with -layers, report imports reaching down more than this many layers at once and exit with a non-zero status")
+)
+
+// layerIndex returns the position in layers of the first prefix matching
+// name, or -1 if name is in none of the layers.
+func layerIndex(name string, layers []string) int {
+	for i, prefix := range layers {
+		if strings.HasPrefix(name, prefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// applyLayers ranks the nodes of g by the layer their import path falls in
+// and colors every edge from a lower layer to a higher one red, reporting
+// it. It returns the number of such violations.
+func applyLayers(g *graph, layers []string) int {
+	g.ranks = make([][]string, len(layers))
+	for _, name := range g.nodes {
+		if i := layerIndex(name, layers); i >= 0 {
+			g.ranks[i] = append(g.ranks[i], name)
+		}
+	}
+
+	violations := 0
+	for _, name := range g.nodes {
+		from := layerIndex(name, layers)
+		if from < 0 {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			if !isUpward(name, imp, layers) {
+				continue
+			}
+			to := layerIndex(imp, layers)
+			violations++
+			report("layering violation: %s (%s) imports %s (%s)\n", name, layers[from], imp, layers[to])
+			g.setEdgeAttr(name, imp, "color", "red")
+			g.setEdgeAttr(name, imp, "constraint", "false")
+		}
+	}
+	return violations
+}
+
+// isUpward reports whether the import of imp by name goes from a lower
+// layer to a higher one.
+func isUpward(name, imp string, layers []string) bool {
+	from, to := layerIndex(name, layers), layerIndex(imp, layers)
+	return from >= 0 && to >= 0 && to < from
+}
+
+// keepLayerViolations reduces g to the imports going up the layers and
+// the packages at both ends.
+func keepLayerViolations(g *graph, layers []string) {
+	g.filterEdges(func(from, to string) bool {
+		return isUpward(from, to, layers)
+	})
+	keep := make(map[string]bool)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			keep[name], keep[imp] = true, true
+		}
+	}
+	g.keepNodes(keep)
+}
+
+// checkLayerSkips colors every edge of g that reaches down more than max
+// layers at once orange and reports it. It returns the number of such
+// edges.
+func checkLayerSkips(g *graph, layers []string, max int) int {
+	skips := 0
+	for _, name := range g.nodes {
+		from := layerIndex(name, layers)
+		if from < 0 {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			to := layerIndex(imp, layers)
+			if to < 0 || to-from <= max {
+				continue
+			}
+			skips++
+			report("layer skip: %s (%s) imports %s (%s), %d layers down\n", name, layers[from], imp, layers[to], to-from)
+			g.setEdgeAttr(name, imp, "color", "orange")
+		}
+	}
+	return skips
+}
+
+// rankLevels ranks the nodes of g by their level: the length of the
+// longest chain of imports from the node down to a package importing
+// nothing. The packages of an import cycle share a level. The deepest
+// level is placed at the top.
+func rankLevels(g *graph) {
+	group := make(map[string]string)
+	for _, scc := range g.cycles() {
+		for _, name := range scc {
+			group[name] = scc[0]
+		}
+	}
+	key := func(name string) string {
+		if k, ok := group[name]; ok {
+			return k
+		}
+		return name
+	}
+
+	// topoOrder lists importers first, so walking it backwards reaches
+	// every package outside of a node's cycle before the node itself.
+	order := g.topoOrder()
+	level := make(map[string]int)
+	max := 0
+	for i := len(order) - 1; i >= 0; i-- {
+		name := order[i]
+		k := key(name)
+		for _, imp := range g.edges[name] {
+			if ik := key(imp); ik != k && level[ik]+1 > level[k] {
+				level[k] = level[ik] + 1
+			}
+		}
+		if level[k] > max {
+			max = level[k]
+		}
+	}
+
+	g.ranks = make([][]string, max+1)
+	for _, name := range g.nodes {
+		r := max - level[key(name)]
+		g.ranks[r] = append(g.ranks[r], name)
+	}
+}
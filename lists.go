@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// splitList splits a comma-separated flag value, returning nil for an
+// empty string.
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
+
+// readList reads a file holding one entry per line. Blank lines and lines
+// starting with # are skipped.
+func readList(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var list []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list = append(list, line)
+	}
+	return list, s.Err()
+}
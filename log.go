@@ -0,0 +1,93 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// A logLevel is the severity of a log message. Messages below the level
+// selected with -log-level are discarded.
+type logLevel int
+
+const (
+	levelError logLevel = iota
+	levelWarn
+	levelInfo
+	levelDebug
+)
+
+var levelNames = map[string]logLevel{
+	"error": levelError,
+	"warn":  levelWarn,
+	"info":  levelInfo,
+	"debug": levelDebug,
+}
+
+var (
+	logLevelFlag = flag.String("log-level", "warn", "the most verbose messages to log to stderr: error, warn, info or debug")
+
+	currentLevel = levelWarn
+)
+
+// setLogLevel selects the level named by -log-level.
+func setLogLevel() {
+	level, ok := levelNames[*logLevelFlag]
+	if !ok {
+		fatalf("unknown log level %q", *logLevelFlag)
+	}
+	currentLevel = level
+}
+
+func logf(level logLevel, prefix, format string, args ...interface{}) {
+	if level <= currentLevel {
+		log.Printf(prefix+format, args...)
+	}
+}
+
+// exitHooks are run by exit, most recently registered first.
+var exitHooks []func()
+
+// atExit registers fn to be run when godepgraph exits through exit or
+// fatalf, which skip deferred calls.
+func atExit(fn func()) {
+	exitHooks = append(exitHooks, fn)
+}
+
+// exit runs the exit hooks and exits with the given status.
+func exit(code int) {
+	for i := len(exitHooks) - 1; i >= 0; i-- {
+		exitHooks[i]()
+	}
+	os.Exit(code)
+}
+
+// fatalf logs an error and exits with a non-zero status.
+func fatalf(format string, args ...interface{}) {
+	errorf(format, args...)
+	exit(1)
+}
+
+func errorf(format string, args ...interface{}) {
+	logf(levelError, "error: ", format, args...)
+}
+
+func warnf(format string, args ...interface{}) {
+	logf(levelWarn, "warning: ", format, args...)
+}
+
+func infof(format string, args ...interface{}) {
+	logf(levelInfo, "", format, args...)
+}
+
+func debugf(format string, args ...interface{}) {
+	logf(levelDebug, "debug: ", format, args...)
+}
+
+// report writes the output of a report requested on the command line to
+// stderr. Reports are not subject to -log-level, and keep stdout free for
+// the graph itself.
+func report(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
 	"go/build"
-	"log"
+	"go/parser"
+	"go/token"
+	"io"
 	"os"
-	"sort"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 var (
@@ -15,6 +20,14 @@ var (
 	ids    map[string]int
 	nextId int
 
+	// roots holds the import paths of the packages named on the command
+	// line.
+	roots []string
+
+	// blankImports records, per package, the imports that only appear
+	// as blank imports (import _ "x").
+	blankImports map[string]map[string]bool
+
 	ignored = map[string]bool{
 		"C": true,
 	}
@@ -29,6 +42,19 @@ var (
 	tagList        = flag.String("tags", "", "a comma-separated list of build tags to consider satisified during the build")
 	horizontal     = flag.Bool("horizontal", false, "lay out the dependency graph horizontally instead of vertically")
 	includeTests   = flag.Bool("t", false, "include test packages")
+	testScope      = flag.String("test-scope", "both", "which test imports -t includes: \"internal\" (package foo tests), \"external\" (package foo_test tests) or \"both\"")
+	selfEdges      = flag.Bool("self-edges", false, "keep self-references such as foo_test importing foo (only relevant with -t)")
+	baseLabelsFlag = flag.Bool("base-labels", false, "label nodes by the last element of their import path, disambiguating collisions")
+	tagsA          = flag.String("tags-a", "", "a comma-separated list of build tags for the first graph to diff (with -tags-b)")
+	tagsB          = flag.String("tags-b", "", "a comma-separated list of build tags for the second graph to diff (with -tags-a)")
+	dedupNested    = flag.String("dedup-nested", "", "when a package imports both a package and its descendant, hide the edge to the \"descendant\" or the \"parent\"")
+	format         = flag.String("format", "dot", "output format: dot, prometheus, cypher, ndjson, dsm, treemap, adjlist, gexf or plantuml")
+	subtree        = flag.String("subtree", "", "only render the packages reachable from this package")
+	subsetFile     = flag.String("subset", "", "a file listing one package per line; only the graph induced by those packages is rendered")
+	pinRoot        = flag.Bool("pin-root", false, "pin the root package to the top of the graph (left with -horizontal)")
+	cgoMode        = flag.String("cgo", "full", "how to traverse packages that use cgo: \"full\", \"shallow\" (don't follow their imports) or \"skip\" (ignore them)")
+	cgoEnabled     = flag.Bool("cgo-enabled", build.Default.CgoEnabled, "consider cgo files and their imports; defaults to the CGO_ENABLED environment setting")
+	markBlank      = flag.Bool("mark-blank-imports", false, "render imports that are only blank imports (import _) with dotted edges")
 
 	buildTags    []string
 	buildContext = build.Default
@@ -37,110 +63,655 @@ var (
 func main() {
 	pkgs = make(map[string]*build.Package)
 	ids = make(map[string]int)
+	blankImports = make(map[string]map[string]bool)
 	flag.Parse()
+	setLogLevel()
+
+	if versionRequested(os.Stdout) {
+		return
+	}
 
 	args := flag.Args()
 
-	if len(args) != 1 {
-		log.Fatal("need one package name to process")
+	if *testsOnly || *testOnlyDeps {
+		*includeTests = true
+	}
+	if _, ok := formats[*format]; !ok {
+		fatalf("unknown output format %q", *format)
+	}
+	if *asSubgraph != "" && !subgraphName.MatchString(*asSubgraph) {
+		fatalf("invalid -as-subgraph name %q", *asSubgraph)
+	}
+	if *preludeFile != "" {
+		data, err := os.ReadFile(*preludeFile)
+		if err != nil {
+			fatalf("failed to read prelude: %s", err)
+		}
+		prelude = data
+	}
+	if err := parseLabelTemplate(); err != nil {
+		fatalf("%s", err)
+	}
+	switch *cgoMode {
+	case "full", "shallow", "skip":
+	default:
+		fatalf("unknown -cgo mode %q", *cgoMode)
+	}
+	switch *testScope {
+	case "internal", "external", "both":
+	default:
+		fatalf("unknown -test-scope %q", *testScope)
+	}
+	if *chunkSize > 0 && !strings.Contains(*chunkOut, "%d") {
+		fatalf("-out needs a %%d for the number of the chunk, got %q", *chunkOut)
+	}
+	if *violationsOnly && *layersFlag == "" {
+		fatalf("-violations-only needs -layers")
+	}
+	if *update && *assertFile == "" {
+		fatalf("-update needs -assert")
+	}
+	switch *dedupNested {
+	case "", "descendant", "parent":
+	default:
+		fatalf("unknown -dedup-nested mode %q", *dedupNested)
 	}
 
-	if *ignorePrefixes != "" {
-		ignoredPrefixes = strings.Split(*ignorePrefixes, ",")
+	cwd, err := os.Getwd()
+	if err != nil {
+		fatalf("failed to get cwd: %s", err)
+	}
+
+	rules, err := loadRules(cwd)
+	if err != nil {
+		fatalf("failed to load rules: %s", err)
 	}
-	if *onlyPrefix != "" {
-		onlyPrefixes = strings.Split(*onlyPrefix, ",")
+	if *printRules {
+		writeRules(os.Stdout, rules)
+		return
 	}
-	if *ignorePackages != "" {
-		for _, p := range strings.Split(*ignorePackages, ",") {
-			ignored[p] = true
+	ignoredPrefixes = rules.settings["ignore-prefixes"]
+	onlyPrefixes = rules.settings["only-prefixes"]
+	for _, p := range rules.settings["ignore"] {
+		ignored[p] = true
+	}
+
+	if *commonDeps {
+		if len(args) < 2 {
+			fatalf("-common-deps needs at least two package names to process")
 		}
+	} else if len(args) != 1 && *binaryFile == "" && *compare == "" && *serveAddr == "" && *ancestors == "" && *testClosure == "" {
+		fatalf("need one package name to process")
 	}
+
 	if *tagList != "" {
 		buildTags = strings.Split(*tagList, ",")
 	}
 	buildContext.BuildTags = buildTags
+	buildContext.CgoEnabled = *cgoEnabled
 
-	cwd, err := os.Getwd()
+	if len(withModules) == 0 {
+		if err := fixWorkspaceFlags(cwd); err != nil {
+			fatalf("%s", err)
+		}
+	}
+	if len(withModules) > 0 {
+		cleanup, err := useModuleVersions(cwd)
+		if err != nil {
+			fatalf("%s", err)
+		}
+		// The exit hook covers fatalf and exit, which skip the
+		// deferred call.
+		defer cleanup()
+		atExit(cleanup)
+	}
+
+	if *stream {
+		if err := streamRoot(cwd, args[0]); err != nil {
+			fatalf("%s", err)
+		}
+		return
+	}
+	if *serveAddr != "" {
+		if err := serve(*serveAddr, cwd); err != nil {
+			fatalf("%s", err)
+		}
+		return
+	}
+
+	if *chunkSize > 0 {
+		failed, err := writeChunks(cwd, args[0], *chunkSize)
+		if err != nil {
+			fatalf("%s", err)
+		}
+		if failed {
+			exit(1)
+		}
+		return
+	}
+
+	var g *graph
+	failed := false
+	switch {
+	case *binaryFile != "":
+		g, err = binaryGraph(*binaryFile)
+	case *compare != "":
+		err = processCompare(cwd)
+		g = newGraph()
+	case *ancestors != "":
+		g, err = ancestorGraph(cwd, *ancestors)
+	case *testClosure != "":
+		g, err = testClosureGraph(cwd, *testClosure)
+	case *impact != "":
+		g, err = ancestorGraph(cwd, *impact+","+args[0])
+	case *previewImport != "":
+		g, err = previewImportGraph(cwd, args[0], *previewImport)
+	case *tagsA != "" || *tagsB != "":
+		g, err = tagDiffGraph(cwd, args[0])
+	case *goosDiff != "":
+		g, err = goosDiffGraph(cwd, args[0])
+	case *timeout > 0:
+		var incomplete bool
+		incomplete, err = processRootWithTimeout(cwd, args[0])
+		g = newGraph()
+		if incomplete {
+			g.comments = append(g.comments, fmt.Sprintf("incomplete: traversal timed out after %s", *timeout))
+			failed = true
+		}
+	case *commonDeps:
+		for _, arg := range args {
+			if err = processRoot(cwd, arg); err != nil {
+				break
+			}
+		}
+		g = newGraph()
+	case strings.Contains(args[0], "..."):
+		var paths []string
+		paths, err = expandRoots(cwd, args[0])
+		for _, path := range paths {
+			if err = processRoot(cwd, path); err != nil {
+				break
+			}
+		}
+		g = newGraph()
+	default:
+		err = processRoot(cwd, args[0])
+		g = newGraph()
+	}
 	if err != nil {
-		log.Fatalf("failed to get cwd: %s", err)
+		fatalf("%s", err)
 	}
-	if err := processPackage(cwd, args[0]); err != nil {
-		log.Fatal(err)
+
+	if writeGraph(os.Stdout, g, cwd) || failed {
+		exit(1)
 	}
+}
 
-	fmt.Println("digraph godep {")
-	if *horizontal {
-		fmt.Println(`rankdir="LR"`)
+// writeGraph transforms, styles and checks g as requested by the flags and
+// writes it, or the report asked for instead, to out. It reports whether
+// a check failed.
+func writeGraph(out io.Writer, g *graph, cwd string) bool {
+	failed := false
+	w := bufio.NewWriter(out)
+
+	// Some modes print a text report instead of the graph.
+	var write func(io.Writer) error
+	switch {
+	case *dumpPackages:
+		write = writePackages
+	case *unreachablePattern != "":
+		write = func(w io.Writer) error {
+			return writeUnreachable(w, cwd, *unreachablePattern)
+		}
+	case *compare != "":
+		write = func(w io.Writer) error {
+			return writeCompare(w, g)
+		}
+	case *statsFlag:
+		write = func(w io.Writer) error {
+			return writeStats(w, g)
+		}
+	case *why != "":
+		write = func(w io.Writer) error {
+			return writeWhy(w, g, *why)
+		}
+	case *graphHash:
+		write = func(w io.Writer) error {
+			return writeGraphHash(w, g)
+		}
+	case *impact != "":
+		write = func(w io.Writer) error {
+			return writeImpact(w, g)
+		}
+	}
+	if write != nil {
+		if err := write(w); err != nil {
+			fatalf("%s", err)
+		}
+		if err := w.Flush(); err != nil {
+			fatalf("%s", err)
+		}
+		return false
 	}
 
-	// sort packages
-	pkgKeys := []string{}
-	for k := range pkgs {
-		pkgKeys = append(pkgKeys, k)
+	if *profileTop > 0 {
+		reportProfile(*profileTop)
 	}
-	sort.Strings(pkgKeys)
 
-	for _, pkgName := range pkgKeys {
-		pkg := pkgs[pkgName]
-		pkgId := getId(pkgName)
+	infof("collected %d packages, graph has %d packages and %d edges", len(pkgs), len(g.nodes), g.numEdges())
 
-		if isIgnored(pkg) {
-			continue
+	if *subtree != "" {
+		if !g.hasNode(*subtree) {
+			fatalf("package %s is not in the graph", *subtree)
+		}
+		g.keepNodes(g.reachable(*subtree))
+		g.roots = []string{*subtree}
+		g.setNodeAttr(*subtree, "shape", "box")
+	}
+	if *prune != "" {
+		if !g.hasNode(*prune) {
+			fatalf("package %s is not in the graph", *prune)
+		}
+		for _, root := range g.roots {
+			if root == *prune {
+				fatalf("cannot prune the root package %s", root)
+			}
 		}
+		prunePackage(g, *prune)
+	}
+	if *codeowners != "" {
+		if err := keepOwned(g, *codeowners); err != nil {
+			fatalf("%s", err)
+		}
+	}
+	if *commonDeps {
+		keepCommonDeps(g)
+	}
+	if *spine != "" {
+		targets := splitList(*spine)
+		for _, name := range targets {
+			if !g.hasNode(name) {
+				fatalf("package %s is not in the graph", name)
+			}
+		}
+		keepSpine(g, targets)
+	}
+	if *subsetFile != "" {
+		subset, err := readList(*subsetFile)
+		if err != nil {
+			fatalf("failed to read subset: %s", err)
+		}
+		keep := make(map[string]bool)
+		for _, name := range subset {
+			if !g.hasNode(name) {
+				warnf("%s from %s is not in the graph", name, *subsetFile)
+			}
+			keep[name] = true
+		}
+		g.keepNodes(keep)
+	}
+	if *testOnlyDeps {
+		reportTestOnlyDeps(g)
+	}
+	if *testsOnly {
+		n := keepTestEdges(g)
+		report("%d packages are only imported by tests\n", n)
+	}
+	if *dedupNested != "" {
+		g.dedupNested(*dedupNested)
+	}
+	if *dominatorTreeFlag {
+		g = dominatorTree(g)
+	}
+	if *edgesTo != "" {
+		keepEdgesTo(g, *edgesTo)
+	}
+	if *directUnion {
+		keepDirectUnion(g)
+	}
+	if *externalOnly {
+		keepExternalEdges(g)
+	}
+	if *externalTouch {
+		keepExternalTouchpoints(g)
+	}
+	if *minFanin > 0 {
+		dropLowFanin(g, *minFanin)
+	}
+	if *infra != "" {
+		hideInfraEdges(g, splitList(*infra))
+	}
+	if *cycleContext >= 0 {
+		n := keepCycleContext(g, *cycleContext)
+		report("%d import cycles\n", n)
+	}
+	if *condense {
+		condenseCycles(g)
+	}
+	if *expand != "" {
+		collapseUnexpanded(g, *expand)
+	}
+	if len(facades) > 0 {
+		collapseFacades(g, facades)
+	}
+	if *collapseCgo {
+		collapseCgoPackages(g)
+	}
+	if *collapseStdlib {
+		collapseStd(g)
+	}
 
-		var color string
-		if pkg.Goroot {
-			color = "palegreen"
-		} else if len(pkg.CgoFiles) > 0 {
-			color = "darkgoldenrod1"
-		} else {
-			color = "paleturquoise"
+	if *boundary != "" {
+		markBoundary(g, *boundary)
+	}
+	if *bidirectional {
+		mergeBidirectional(g)
+	}
+	if *markVendor {
+		markVendorEdges(g)
+	}
+	if *edgeReasons {
+		markEdgeReasons(g)
+	}
+	if *edgeProvenance {
+		markEdgeProvenance(g)
+	}
+	if *edgeAge {
+		markEdgeAge(g)
+	}
+	if *marginal {
+		markMarginal(g)
+	}
+	if *edgeImpactFlag {
+		markEdgeImpact(g)
+	}
+	if *symbolCoupling {
+		if err := markSymbolCoupling(g); err != nil {
+			fatalf("%s", err)
+		}
+	}
+	if *layoutWeights {
+		if err := markLayoutWeights(g); err != nil {
+			fatalf("%s", err)
 		}
+	}
+	if *stdlibGroups {
+		colorStdlibGroups(g)
+	}
+	if *metadataCmd != "" {
+		addMetadata(g, *metadataCmd)
+	}
+	if *highlightModule != "" {
+		own, only := markModuleFootprint(g, *highlightModule)
+		report("%s provides %d packages and is the only way in for %d more\n", *highlightModule, own, only)
+	}
+	if *showReplaces {
+		if err := markReplaced(g); err != nil {
+			fatalf("%s", err)
+		}
+	}
 
-		fmt.Printf("_%d [label=\"%s\" style=\"filled\" color=\"%s\"];\n", pkgId, pkgName, color)
+	if *treeClusters {
+		clusterTree(g)
+	}
+	if *groupsFile != "" {
+		rules, err := readGroups(*groupsFile)
+		if err != nil {
+			fatalf("failed to read groups: %s", err)
+		}
+		clusterGroups(g, rules)
+	}
+	if *workspace {
+		if err := clusterWorkspace(g, cwd); err != nil {
+			fatalf("%s", err)
+		}
+	}
 
-		// Don't render imports from packages in Goroot
-		if pkg.Goroot && !*delveGoroot {
-			continue
+	if *edgePopularity {
+		markEdgePopularity(g)
+	}
+	if *sizeByCompiled {
+		if err := markCompiledSize(g, cwd); err != nil {
+			fatalf("%s", err)
 		}
+	}
+	if *shadeByClosure {
+		markClosureShading(g)
+	}
+	if *autoColors {
+		markAutoColors(g, *colorSeed)
+	}
 
-		for _, imp := range getImports(pkg) {
-			impPkg := pkgs[imp]
-			if impPkg == nil || isIgnored(impPkg) {
-				continue
+	if *rankByLevel {
+		rankLevels(g)
+	}
+	if *layersFlag != "" {
+		if n := applyLayers(g, splitList(*layersFlag)); n > 0 {
+			report("%d layering violations\n", n)
+		}
+		if *violationsOnly {
+			keepLayerViolations(g, splitList(*layersFlag))
+		}
+		if *maxLayerSkip > 0 {
+			if n := checkLayerSkips(g, splitList(*layersFlag), *maxLayerSkip); n > 0 {
+				report("%d imports skip more than %d layers\n", n, *maxLayerSkip)
+				failed = true
 			}
+		}
+	}
+	if *checkGoModFlag {
+		if err := checkGoMod(g); err != nil {
+			fatalf("%s", err)
+		}
+	}
+	if *stdlibUsage {
+		reportStdlibUsage(g)
+	}
+	if *edgeSummary {
+		reportEdgeSummary(g)
+	}
+	if *coupled {
+		reportCoupled(g)
+	}
+	if *diamonds > 0 {
+		reportDiamonds(g, *diamonds)
+	}
+	if *specFile != "" {
+		s, err := readSpec(*specFile)
+		if err != nil {
+			fatalf("failed to read spec: %s", err)
+		}
+		if n := checkSpec(g, s); n > 0 {
+			report("%d imports violate %s\n", n, *specFile)
+			failed = true
+		}
+	}
+	if *noMix != "" {
+		a, b, ok := parseNoMix(*noMix)
+		if !ok {
+			fatalf("-no-mix needs two comma-separated prefixes, got %q", *noMix)
+		}
+		if n := checkNoMix(g, a, b); n > 0 && *failOnMix {
+			failed = true
+		}
+	}
+	if *maxFanout > 0 {
+		if n := checkFanout(g, *maxFanout); n > 0 && *failOnFat {
+			failed = true
+		}
+	}
+	if *stable != "" {
+		if n := checkStable(g, splitList(*stable)); n > 0 {
+			report("%d imports of unstable packages by stable ones\n", n)
+			if *failOnUnstable {
+				failed = true
+			}
+		}
+	}
+	if *checkInternalFlag {
+		if n := checkInternal(g); n > 0 {
+			report("%d questionable imports of internal packages\n", n)
+		}
+	}
+	if *checkDupes {
+		if n := reportDirDupes(); n > 0 && *failOnDupes {
+			failed = true
+		}
+	}
+	if *allowedFile != "" {
+		entries, err := readList(*allowedFile)
+		if err != nil {
+			fatalf("failed to read allowlist: %s", err)
+		}
+		if n := checkAllowed(g, entries); n > 0 {
+			report("%d packages are not in %s\n", n, *allowedFile)
+			failed = true
+		}
+	}
+	if *deprecatedList != "" {
+		if n := checkDeprecated(g); n > 0 && *failOnDeprecated {
+			failed = true
+		}
+	}
+	if *assertFile != "" && *update {
+		if err := writeGolden(*assertFile, g); err != nil {
+			fatalf("failed to write %s: %s", *assertFile, err)
+		}
+		report("updated %s\n", *assertFile)
+	} else if *assertFile != "" {
+		n, err := assertGolden(*assertFile, g)
+		if err != nil {
+			fatalf("%s", err)
+		}
+		if n > 0 {
+			report("%d differences from %s\n", n, *assertFile)
+			failed = true
+		}
+	}
+
+	if *anonymizeFile != "" {
+		if err := anonymize(g, *anonymizeFile); err != nil {
+			fatalf("failed to anonymize: %s", err)
+		}
+	}
+
+	if *jsonDir != "" {
+		if err := writeJSONDir(*jsonDir, g); err != nil {
+			fatalf("failed to write JSON files: %s", err)
+		}
+	}
+	if *fileMap != "" {
+		if err := writeFileMap(*fileMap, g); err != nil {
+			fatalf("failed to write file map: %s", err)
+		}
+	}
 
-			impId := getId(imp)
-			fmt.Printf("_%d -> _%d;\n", pkgId, impId)
+	if *svgFile != "" || *pngFile != "" {
+		if err := renderImages(g); err != nil {
+			fatalf("%s", err)
 		}
+	} else if err := formats[*format](w, g); err != nil {
+		fatalf("%s", err)
+	}
+	if err := w.Flush(); err != nil {
+		fatalf("%s", err)
+	}
+	return failed
+}
+
+// formats maps the names accepted by -format to their writers.
+var formats = map[string]func(io.Writer, *graph) error{
+	"dot":        writeDot,
+	"prometheus": writePrometheus,
+	"cypher":     writeCypher,
+	"ndjson":     writeNDJSON,
+	"dsm":        writeDSM,
+	"treemap":    writeTreemap,
+	"adjlist":    writeAdjList,
+	"gexf":       writeGEXF,
+	"plantuml":   writePlantUML,
+}
+
+// processRoot processes pkgName and records it as one of the roots of the
+// graph.
+func processRoot(root string, pkgName string) error {
+	pkg, err := buildContext.Import(pkgName, root, build.FindOnly)
+	if err != nil {
+		return fmt.Errorf("failed to import %s: %s", pkgName, err)
+	}
+	if err := processPackage(root, pkgName); err != nil {
+		return err
 	}
-	fmt.Println("}")
+	return withPkgs(func() {
+		roots = append(roots, canonicalPath(resolvedImportPath(pkg)))
+	})
 }
 
 func processPackage(root string, pkgName string) error {
-	if ignored[pkgName] {
-		return nil
+	var skip bool
+	if err := withPkgs(func() { skip = ignored[pkgName] }); err != nil || skip {
+		return err
 	}
 
-	pkg, err := buildContext.Import(pkgName, root, 0)
+	debugf("importing %s", pkgName)
+	start := time.Now()
+	pkg, err := importPackage(pkgName, root)
+	if *profileTop > 0 {
+		recordImport(pkgName, time.Since(start))
+	}
+	if *allTags && constrainedOut(pkg, err) {
+		err = nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to import %s: %s", pkgName, err)
 	}
+	pkg.ImportPath = resolvedImportPath(pkg)
+	if *allTags {
+		if err := addConstrainedImports(pkg); err != nil {
+			return err
+		}
+	}
 
-	if isIgnored(pkg) {
-		return nil
+	var blank map[string]bool
+	var skipBlank bool
+	if err := withPkgs(func() { skipBlank = isIgnored(pkg) }); err != nil {
+		return err
+	}
+	if *markBlank && !skipBlank {
+		blank, err = findBlankImports(pkg)
+		if err != nil {
+			return err
+		}
 	}
 
-	pkgs[pkg.ImportPath] = pkg
+	pkgsMu.Lock()
+	added, err := addPackage(pkgName, pkg, blank)
+	if err == nil && *stream {
+		streamPackage(pkgName, pkg, added)
+	}
+	pkgsMu.Unlock()
+	if !added || err != nil {
+		return err
+	}
 
 	// Don't worry about dependencies for stdlib packages
 	if pkg.Goroot && !*delveGoroot {
 		return nil
 	}
+	if *cgoMode == "shallow" && len(pkg.CgoFiles) > 0 {
+		return nil
+	}
 
-	for _, imp := range getImports(pkg) {
-		if _, ok := pkgs[imp]; !ok {
+	var imports []string
+	if err := withPkgs(func() { imports = getImports(pkg) }); err != nil {
+		return err
+	}
+	for _, imp := range imports {
+		var seen bool
+		if err := withPkgs(func() { _, seen = pkgs[imp] }); err != nil {
+			return err
+		}
+		if !seen {
 			if err := processPackage(root, imp); err != nil {
 				return err
 			}
@@ -149,16 +720,58 @@ func processPackage(root string, pkgName string) error {
 	return nil
 }
 
+// addPackage records pkg, imported as pkgName, in pkgs. It reports false
+// if the package is ignored or turns out to be a package already seen
+// under another import path.
+func addPackage(pkgName string, pkg *build.Package, blank map[string]bool) (bool, error) {
+	if err := traversal.Err(); err != nil {
+		return false, err
+	}
+
+	if pkgName != pkg.ImportPath {
+		aliases[pkgName] = pkg.ImportPath
+	}
+
+	if *cgoMode == "skip" && len(pkg.CgoFiles) > 0 {
+		debugf("ignoring cgo package %s", pkg.ImportPath)
+		ignored[pkg.ImportPath] = true
+	}
+	if *skipGenerated && !pkg.Goroot && isGenerated(pkg) {
+		debugf("ignoring generated package %s", pkg.ImportPath)
+		ignored[pkg.ImportPath] = true
+	}
+	if isIgnored(pkg) {
+		return false, nil
+	}
+
+	if other, ok := seenDir(pkg); ok {
+		debugf("%s is the same package as %s", pkg.ImportPath, other)
+		aliases[pkgName] = other
+		aliases[pkg.ImportPath] = other
+		if pkg.ImportPath != other {
+			dirDupes[pkg.ImportPath] = other
+		}
+		return false, nil
+	}
+	pkgs[pkg.ImportPath] = pkg
+	if blank != nil {
+		blankImports[pkg.ImportPath] = blank
+	}
+	return true, nil
+}
+
+// getImports returns the imports of pkg, by the import paths they are
+// keyed under in pkgs.
 func getImports(pkg *build.Package) []string {
-	allImports := pkg.Imports
+	allImports := append([]string{}, pkg.Imports...)
 	if *includeTests {
-		allImports = append(allImports, pkg.TestImports...)
-		allImports = append(allImports, pkg.XTestImports...)
+		allImports = append(allImports, testImports(pkg)...)
 	}
 	var imports []string
 	found := make(map[string]struct{})
 	for _, imp := range allImports {
-		if imp == pkg.ImportPath {
+		imp = canonicalPath(imp)
+		if imp == pkg.ImportPath && !*selfEdges {
 			// Don't draw a self-reference when foo_test depends on foo.
 			continue
 		}
@@ -171,6 +784,115 @@ func getImports(pkg *build.Package) []string {
 	return imports
 }
 
+// testImports returns the imports of the tests of pkg that are in the
+// scope selected by -test-scope.
+func testImports(pkg *build.Package) []string {
+	var imports []string
+	if *testScope != "external" {
+		imports = append(imports, pkg.TestImports...)
+	}
+	if *testScope != "internal" {
+		imports = append(imports, pkg.XTestImports...)
+	}
+	return imports
+}
+
+// sourceFiles returns the names of the Go files of pkg, including those
+// of its tests in the scope selected by -test-scope when -t is given.
+func sourceFiles(pkg *build.Package) []string {
+	files := append([]string{}, pkg.GoFiles...)
+	files = append(files, pkg.CgoFiles...)
+	if *includeTests {
+		if *testScope != "external" {
+			files = append(files, pkg.TestGoFiles...)
+		}
+		if *testScope != "internal" {
+			files = append(files, pkg.XTestGoFiles...)
+		}
+	}
+	return files
+}
+
+// findBlankImports parses the import declarations of the package's files
+// and returns the set of imports that are never imported under a usable
+// name. go/build only reports import paths, so the files have to be
+// parsed again to tell blank imports apart.
+func findBlankImports(pkg *build.Package) (map[string]bool, error) {
+	files := sourceFiles(pkg)
+
+	blank := make(map[string]bool)
+	used := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, name := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, name), nil, parser.ImportsOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", name, err)
+		}
+		for _, spec := range f.Imports {
+			path, _ := strconv.Unquote(spec.Path.Value)
+			if spec.Name != nil && spec.Name.Name == "_" {
+				blank[path] = true
+			} else {
+				used[path] = true
+			}
+		}
+	}
+	for path := range used {
+		delete(blank, path)
+	}
+	return blank, nil
+}
+
+// isTestImport reports whether pkg imports imp, by its canonical path,
+// only from its tests.
+func isTestImport(pkg *build.Package, imp string) bool {
+	for _, i := range pkg.Imports {
+		if canonicalPath(i) == imp {
+			return false
+		}
+	}
+	for _, i := range testImports(pkg) {
+		if canonicalPath(i) == imp {
+			return true
+		}
+	}
+	return false
+}
+
+// importReasons returns how pkg imports imp, by its canonical path: any
+// of "import" for its regular files and "test" and "xtest" for its
+// internal and external tests, in that order.
+func importReasons(pkg *build.Package, imp string) []string {
+	var reasons []string
+	for _, src := range []struct {
+		reason  string
+		imports []string
+	}{
+		{"import", pkg.Imports},
+		{"test", pkg.TestImports},
+		{"xtest", pkg.XTestImports},
+	} {
+		for _, i := range src.imports {
+			if canonicalPath(i) == imp {
+				reasons = append(reasons, src.reason)
+				break
+			}
+		}
+	}
+	return reasons
+}
+
+// isBlankImport reports whether every import of imp by the package from
+// is a blank import.
+func isBlankImport(from, imp string) bool {
+	for path := range blankImports[from] {
+		if canonicalPath(path) == imp {
+			return true
+		}
+	}
+	return false
+}
+
 func getId(name string) int {
 	id, ok := ids[name]
 	if !ok {
@@ -196,11 +918,3 @@ func isIgnored(pkg *build.Package) bool {
 	}
 	return ignored[pkg.ImportPath] || (pkg.Goroot && *ignoreStdlib) || hasPrefixes(pkg.ImportPath, ignoredPrefixes)
 }
-
-func debug(args ...interface{}) {
-	fmt.Fprintln(os.Stderr, args...)
-}
-
-func debugf(s string, args ...interface{}) {
-	fmt.Fprintf(os.Stderr, s, args...)
-}
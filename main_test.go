@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// runMain starts the test binary with this set to run godepgraph
+	// itself.
+	if os.Getenv("GODEPGRAPH_RUN_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// writeFiles creates files under dir, keyed by their slash separated path
+// relative to dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// useGOPATH makes the traversal run in GOPATH mode against gopath, starting
+// from an empty set of packages. Both are restored when the test ends.
+func useGOPATH(t *testing.T, gopath string) {
+	t.Helper()
+	t.Setenv("GO111MODULE", "off")
+	freshTraversal(t)
+	buildContext.GOPATH = gopath
+}
+
+// useModules makes the traversal run in module mode, without network
+// access or GOFLAGS from the environment, starting from an empty set of packages. Both are restored when
+// the test ends.
+func useModules(t *testing.T) {
+	t.Helper()
+	t.Setenv("GO111MODULE", "on")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOWORK", "")
+	t.Setenv("GOFLAGS", "")
+	freshTraversal(t)
+	buildContext.GOPATH = t.TempDir()
+	t.Setenv("GOPATH", buildContext.GOPATH)
+}
+
+// chdir changes the working directory to dir, which is where the go
+// command resolves module imports from, until the test ends.
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// freshTraversal forgets every package collected so far and the node ids
+// handed out, and restores them along with buildContext when the test
+// ends.
+func freshTraversal(t *testing.T) {
+	t.Helper()
+	saved := buildContext
+	t.Cleanup(func() {
+		buildContext = saved
+		resetPackages()
+	})
+	resetPackages()
+	ids, nextId = make(map[string]int), 0
+}
+
+// traverse collects the packages reachable from pkgName, imported from
+// srcDir, and returns their graph.
+func traverse(t *testing.T, srcDir, pkgName string) *graph {
+	t.Helper()
+	if err := processRoot(srcDir, pkgName); err != nil {
+		t.Fatal(err)
+	}
+	return newGraph()
+}
+
+// assertEdges fails the test unless g holds exactly the given edges.
+func assertEdges(t *testing.T, g *graph, want map[string][]string) {
+	t.Helper()
+	got := make(map[string][]string)
+	for name, imps := range g.edges {
+		if len(imps) > 0 {
+			got[name] = imps
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("edges = %v, want %v", got, want)
+	}
+}
+
+// setFlag sets the string flag p points to for the duration of the test.
+func setFlag(t *testing.T, p *string, value string) {
+	t.Helper()
+	saved := *p
+	t.Cleanup(func() { *p = saved })
+	*p = value
+}
+
+// setBoolFlag sets the bool flag p points to for the duration of the test.
+func setBoolFlag(t *testing.T, p *bool, value bool) {
+	t.Helper()
+	saved := *p
+	t.Cleanup(func() { *p = saved })
+	*p = value
+}
+
+func TestCgoEnabled(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprint(enabled), func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, map[string]string{
+				"src/ex/app/app.go": "package app\n\nimport _ \"ex/lib\"\n",
+				"src/ex/app/cgo.go": "package app\n\n// #include <stdlib.h>\nimport \"C\"\n\nimport _ \"ex/onlyc\"\n",
+				"src/ex/lib/lib.go": "package lib\n",
+				"src/ex/onlyc/c.go": "package onlyc\n",
+			})
+			useGOPATH(t, dir)
+			buildContext.CgoEnabled = enabled
+
+			want := map[string][]string{"ex/app": {"ex/lib"}}
+			if enabled {
+				want["ex/app"] = append(want["ex/app"], "ex/onlyc")
+			}
+			assertEdges(t, traverse(t, dir, "ex/app"), want)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"os/exec"
+	"strings"
+)
+
+var metadataCmd = flag.String("metadata-cmd", "", "run this command with each package's import path and merge the key=value;... DOT attributes it prints into the node")
+
+// addMetadata runs cmd for every node of g, with the import path of the
+// package appended to its arguments, and sets the DOT attributes from the
+// first line of its output, given as key=value pairs separated by
+// semicolons. Packages for which the command fails are left alone.
+func addMetadata(g *graph, cmd string) {
+	args := strings.Fields(cmd)
+	if len(args) == 0 {
+		return
+	}
+	for _, name := range g.nodes {
+		out, err := exec.Command(args[0], append(args[1:], name)...).Output()
+		if err != nil {
+			warnf("metadata command failed for %s: %s", name, err)
+			continue
+		}
+		line := strings.SplitN(string(out), "\n", 2)[0]
+		for _, pair := range strings.Split(line, ";") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				warnf("metadata command printed invalid attribute %q for %s", pair, name)
+				continue
+			}
+			g.setNodeAttr(name, strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+		}
+	}
+}
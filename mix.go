@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"strings"
+)
+
+var (
+	noMix     = flag.String("no-mix", "", "two comma-separated import path prefixes, A,B; packages directly importing both are highlighted and reported")
+	failOnMix = flag.Bool("fail-on-mix", false, "exit with a non-zero status if any package violates -no-mix")
+)
+
+// checkNoMix colors the packages in g that directly import both a package
+// under prefix a and one under prefix b red, and lists them on stderr. It
+// returns the number of offending packages.
+func checkNoMix(g *graph, a, b string) int {
+	n := 0
+	for _, name := range g.nodes {
+		var impA, impB string
+		for _, imp := range g.edges[name] {
+			if impA == "" && strings.HasPrefix(imp, a) {
+				impA = imp
+			}
+			if impB == "" && strings.HasPrefix(imp, b) {
+				impB = imp
+			}
+		}
+		if impA == "" || impB == "" {
+			continue
+		}
+		n++
+		g.setNodeAttr(name, "color", "red")
+		report("%s imports both %s and %s\n", name, impA, impB)
+	}
+	return n
+}
+
+// parseNoMix splits the value of -no-mix into its two prefixes.
+func parseNoMix(s string) (a, b string, ok bool) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// moduleRoots caches the module path found for each directory looked at
+// by findModule. Directories outside of any module map to "".
+var moduleRoots = make(map[string]string)
+
+// syntheticModules maps the import paths of synthetic packages standing
+// for a whole module, such as the ones -expand draws, to that module.
+var syntheticModules = make(map[string]string)
+
+// gopathModule is the synthetic module that packages outside of any
+// module, as found in GOPATH mode, are grouped under.
+const gopathModule = "GOPATH"
+
+// warnedNoModule is set once the user has been told that some packages
+// are not in a module.
+var warnedNoModule bool
+
+// moduleOf returns the path of the module containing pkg, or "" if pkg
+// is part of the standard library. Packages that are not inside a module
+// belong to gopathModule.
+func moduleOf(pkg *build.Package) string {
+	if mod, ok := syntheticModules[pkg.ImportPath]; ok {
+		return mod
+	}
+	if pkg.Goroot || pkg.Dir == "" {
+		return ""
+	}
+	if mod := findModule(pkg.Dir); mod != "" {
+		return mod
+	}
+	if mod := cachedModule(pkg.Dir); mod != "" {
+		return mod
+	}
+	warnNoModule(pkg.ImportPath)
+	return gopathModule
+}
+
+// firstParty returns a predicate reporting whether a package of g is part
+// of the module of the first root of g.
+func firstParty(g *graph) func(name string) bool {
+	if len(g.roots) == 0 {
+		return func(string) bool { return false }
+	}
+	mod := moduleOf(pkgs[g.roots[0]])
+	return func(name string) bool {
+		pkg := pkgs[name]
+		return !pkg.Goroot && moduleOf(pkg) == mod
+	}
+}
+
+// packageKind returns "stdlib" for a package of the standard library,
+// "first-party" for one that isFirstParty reports true for and
+// "external" for any other.
+func packageKind(isFirstParty func(string) bool, name string) string {
+	switch {
+	case pkgs[name].Goroot:
+		return "stdlib"
+	case isFirstParty(name):
+		return "first-party"
+	}
+	return "external"
+}
+
+// warnNoModule warns, the first time only, that the named package is not
+// part of a module.
+func warnNoModule(name string) {
+	if warnedNoModule {
+		return
+	}
+	warnedNoModule = true
+	warnf("%s is not in a module; grouping packages outside of modules as %s", name, gopathModule)
+}
+
+// findModule walks up from dir looking for a go.mod file and returns the
+// module path it declares.
+func findModule(dir string) string {
+	if mod, ok := moduleRoots[dir]; ok {
+		return mod
+	}
+	var mod string
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		mod = modulePath(data)
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = findModule(parent)
+	}
+	moduleRoots[dir] = mod
+	return mod
+}
+
+// cachedModule returns the path of the module that dir belongs to if dir
+// is inside the module cache, which keeps modules without a go.mod file
+// in directories named path@version.
+func cachedModule(dir string) string {
+	parts := strings.Split(filepath.ToSlash(dir), "/")
+	for i := len(parts) - 1; i > 1; i-- {
+		at := strings.LastIndex(parts[i], "@")
+		if at < 0 {
+			continue
+		}
+		for j := i - 1; j > 0; j-- {
+			if parts[j-1] == "pkg" && parts[j] == "mod" {
+				elems := append(append([]string{}, parts[j+1:i]...), parts[i][:at])
+				return unescapeModulePath(strings.Join(elems, "/"))
+			}
+		}
+		return ""
+	}
+	return ""
+}
+
+// unescapeModulePath undoes the escaping of upper case letters, as !x,
+// used in module cache paths.
+func unescapeModulePath(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		if p[i] == '!' && i+1 < len(p) {
+			i++
+			b.WriteString(strings.ToUpper(p[i : i+1]))
+			continue
+		}
+		b.WriteByte(p[i])
+	}
+	return b.String()
+}
+
+// modulePath returns the module path declared by the contents of a
+// go.mod file.
+func modulePath(data []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		fields := strings.Fields(stripComment(s.Text()))
+		if len(fields) == 2 && fields[0] == "module" {
+			if path, err := strconv.Unquote(fields[1]); err == nil {
+				return path
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// A replacement is a replace directive from a go.mod file.
+type replacement struct {
+	oldPath, oldVersion string
+	newPath, newVersion string
+}
+
+// parseReplaces returns the replace directives in the contents of a go.mod
+// file.
+func parseReplaces(data []byte) []replacement {
+	var reps []replacement
+	inBlock := false
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(stripComment(s.Text()))
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case line == "replace (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "replace "):
+			line = strings.TrimPrefix(line, "replace ")
+		default:
+			continue
+		}
+
+		parts := strings.SplitN(line, "=>", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		old, repl := unquoteFields(parts[0]), unquoteFields(parts[1])
+		if len(old) == 0 || len(repl) == 0 {
+			continue
+		}
+		r := replacement{oldPath: old[0], newPath: repl[0]}
+		if len(old) > 1 {
+			r.oldVersion = old[1]
+		}
+		if len(repl) > 1 {
+			r.newVersion = repl[1]
+		}
+		reps = append(reps, r)
+	}
+	return reps
+}
+
+// A requirement is a require directive from a go.mod file.
+type requirement struct {
+	path, version string
+	indirect      bool
+}
+
+// parseRequires returns the require directives in the contents of a go.mod
+// file.
+func parseRequires(data []byte) []requirement {
+	var reqs []requirement
+	inBlock := false
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		raw := s.Text()
+		line := strings.TrimSpace(stripComment(raw))
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case line == "require (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		default:
+			continue
+		}
+
+		fields := unquoteFields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		indirect := strings.Contains(raw, "// indirect")
+		reqs = append(reqs, requirement{path: fields[0], version: fields[1], indirect: indirect})
+	}
+	return reqs
+}
+
+// findGoMod walks up from dir and returns the path of the first go.mod
+// file found, or "" if there is none.
+func findGoMod(dir string) string {
+	for {
+		file := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// findGoWork returns the path of the go.work file of the workspace that
+// dir is in, or "" if there is none. Like the go command, it honors the
+// GOWORK environment variable.
+func findGoWork(dir string) string {
+	switch env := os.Getenv("GOWORK"); env {
+	case "off":
+		return ""
+	case "":
+	default:
+		return env
+	}
+	for {
+		file := filepath.Join(dir, "go.work")
+		if _, err := os.Stat(file); err == nil {
+			return file
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseWorkUses returns the module directories listed by the use
+// directives in the contents of a go.work file.
+func parseWorkUses(data []byte) []string {
+	var dirs []string
+	inBlock := false
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(stripComment(s.Text()))
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+		case line == "use (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "use "):
+			line = strings.TrimPrefix(line, "use ")
+		default:
+			continue
+		}
+		if fields := unquoteFields(line); len(fields) == 1 {
+			dirs = append(dirs, fields[0])
+		}
+	}
+	return dirs
+}
+
+func unquoteFields(s string) []string {
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if u, err := strconv.Unquote(f); err == nil {
+			fields[i] = u
+		}
+	}
+	return fields
+}
+
+func stripComment(line string) string {
+	if i := strings.Index(line, "//"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestGOPATHModule(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go": "package app\n\nimport (\n\t_ \"ex/lib\"\n\t_ \"fmt\"\n)\n",
+		"src/ex/lib/lib.go": "package lib\n",
+	})
+	useGOPATH(t, dir)
+	saved := warnedNoModule
+	t.Cleanup(func() { warnedNoModule = saved })
+	warnedNoModule = false
+
+	g := traverse(t, dir, "ex/app")
+	for _, name := range []string{"ex/app", "ex/lib"} {
+		if got := moduleOf(pkgs[name]); got != gopathModule {
+			t.Errorf("module of %s = %q, want %q", name, got, gopathModule)
+		}
+	}
+	if got := moduleOf(pkgs["fmt"]); got != "" {
+		t.Errorf("module of fmt = %q, want \"\"", got)
+	}
+	if !warnedNoModule {
+		t.Error("no warning about packages outside of modules")
+	}
+
+	// Modes that look at the root's module must still produce a graph.
+	setBoolFlag(t, directUnion, true)
+	var buf bytes.Buffer
+	if writeGraph(&buf, g, dir) {
+		t.Fatal("writeGraph failed")
+	}
+	for _, label := range []string{`label="ex/app"`, `label="ex/lib"`, `label="fmt"`} {
+		if !strings.Contains(buf.String(), label) {
+			t.Errorf("graph lacks %s:\n%s", label, buf.String())
+		}
+	}
+}
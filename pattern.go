@@ -0,0 +1,139 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var notRoot = flag.String("not-root", "", "an import path pattern, where ... matches anything, of packages matched by the root pattern that are not to be used as roots; they are still graphed when imported")
+
+// matchPattern returns a function reporting whether an import path
+// matches pattern, where "..." matches any string as with the go command.
+// A pattern ending in "/..." also matches the path without that suffix.
+func matchPattern(pattern string) func(string) bool {
+	re := regexp.QuoteMeta(pattern)
+	re = strings.Replace(re, `\.\.\.`, `.*`, -1)
+	if strings.HasSuffix(re, `/.*`) {
+		re = strings.TrimSuffix(re, `/.*`) + `(/.*)?`
+	}
+	reg := regexp.MustCompile(`^` + re + `$`)
+	return reg.MatchString
+}
+
+// expandPattern returns the canonical import paths of the packages that
+// pattern refers to. Patterns without "..." name a single package;
+// otherwise the directory tree below the part of the pattern before the
+// first "..." is searched for packages. Both import paths and relative
+// directories are accepted.
+func expandPattern(root, pattern string) ([]string, error) {
+	i := strings.Index(pattern, "...")
+	if i < 0 {
+		pkg, err := buildContext.Import(pattern, root, build.FindOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %s", pattern, err)
+		}
+		return []string{canonicalPath(resolvedImportPath(pkg))}, nil
+	}
+
+	prefix := pattern[:i]
+	if j := strings.LastIndex(prefix, "/"); j >= 0 {
+		prefix = prefix[:j]
+	} else {
+		prefix = ""
+	}
+
+	local := build.IsLocalImport(pattern) || filepath.IsAbs(pattern)
+	var dir string
+	if local {
+		dir = prefix
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, prefix)
+		}
+	} else {
+		if prefix == "" {
+			return nil, fmt.Errorf("pattern %s is too broad", pattern)
+		}
+		pkg, err := buildContext.Import(prefix, root, build.FindOnly)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find %s: %s", prefix, err)
+		}
+		dir = pkg.Dir
+	}
+
+	var match func(string) bool
+	if local {
+		match = matchPattern(filepath.ToSlash(filepath.Clean(pattern)))
+	} else {
+		match = matchPattern(pattern)
+	}
+
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != dir {
+			name := info.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				// A nested module is not part of the pattern.
+				return filepath.SkipDir
+			}
+		}
+
+		pkg, err := buildContext.ImportDir(path, 0)
+		if err != nil {
+			if _, ok := err.(*build.NoGoError); ok {
+				return nil
+			}
+			return fmt.Errorf("failed to import %s: %s", path, err)
+		}
+		importPath := canonicalPath(resolvedImportPath(pkg))
+		if local {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			if !match(filepath.ToSlash(rel)) {
+				return nil
+			}
+		} else if !match(importPath) {
+			return nil
+		}
+		paths = append(paths, importPath)
+		return nil
+	})
+	return paths, err
+}
+
+// expandRoots returns the packages that pattern refers to, like
+// expandPattern, leaving out those matching -not-root.
+func expandRoots(root, pattern string) ([]string, error) {
+	paths, err := expandPattern(root, pattern)
+	if err != nil || *notRoot == "" {
+		return paths, err
+	}
+	skip := matchPattern(*notRoot)
+	var roots []string
+	for _, path := range paths {
+		if skip(path) {
+			debugf("not using %s as a root", path)
+			continue
+		}
+		roots = append(roots, path)
+	}
+	if len(roots) == 0 {
+		return nil, fmt.Errorf("-not-root %s excludes every package matching %s", *notRoot, pattern)
+	}
+	return roots, nil
+}
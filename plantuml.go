@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writePlantUML writes the graph as a PlantUML component diagram, with a
+// component per package and an arrow per import. The components carry a
+// stereotype for their kind, styled in the colors of the DOT output.
+func writePlantUML(w io.Writer, g *graph) error {
+	fmt.Fprintln(w, "@startuml")
+	fmt.Fprintln(w, "skinparam component {")
+	fmt.Fprintln(w, "  BackgroundColor<<std>> PaleGreen")
+	fmt.Fprintln(w, "  BackgroundColor<<cgo>> #FFB90F")
+	fmt.Fprintln(w, "  BackgroundColor<<pkg>> PaleTurquoise")
+	fmt.Fprintln(w, "}")
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		kind := "pkg"
+		if pkg.Goroot {
+			kind = "std"
+		} else if len(pkg.CgoFiles) > 0 {
+			kind = "cgo"
+		}
+		fmt.Fprintf(w, "component \"%s\" as p%d <<%s>>\n", strings.Replace(name, `"`, `'`, -1), getId(name), kind)
+	}
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			fmt.Fprintf(w, "p%d --> p%d\n", getId(name), getId(imp))
+		}
+	}
+	_, err := fmt.Fprintln(w, "@enduml")
+	return err
+}
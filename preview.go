@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"strings"
+)
+
+var previewImport = flag.String("preview-import", "", "SRC,DEP: only render the packages and imports that would be added to the graph if SRC imported DEP")
+
+// previewImportGraph builds the graph of pkgName and then what it would
+// become if src also imported dep, and returns just the difference: src,
+// the packages only reachable through the new import and the imports they
+// add. Packages already in the graph that the new ones import are kept,
+// uncolored, to show where the addition attaches.
+func previewImportGraph(root, pkgName, arg string) (*graph, error) {
+	parts := strings.SplitN(arg, ",", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("-preview-import needs two comma-separated packages, got %q", arg)
+	}
+	src := parts[0]
+
+	if err := processRoot(root, pkgName); err != nil {
+		return nil, err
+	}
+	before := newGraph()
+	if !before.hasNode(src) {
+		return nil, fmt.Errorf("package %s is not in the graph", src)
+	}
+	had := before.reachable(before.roots...)
+
+	pkg, err := buildContext.Import(parts[1], root, build.FindOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to import %s: %s", parts[1], err)
+	}
+	if err := processPackage(root, parts[1]); err != nil {
+		return nil, err
+	}
+	dep := canonicalPath(resolvedImportPath(pkg))
+	g := newGraph()
+	if !g.hasNode(dep) {
+		return nil, fmt.Errorf("package %s is ignored", dep)
+	}
+	imported := false
+	for _, imp := range g.edges[src] {
+		imported = imported || imp == dep
+	}
+	if !imported {
+		g.edges[src] = append(g.edges[src], dep)
+	}
+
+	added := make(map[string]bool)
+	for name := range g.reachable(g.roots...) {
+		if !had[name] {
+			added[name] = true
+		}
+	}
+	keep := map[string]bool{src: true}
+	edges := 0
+	g.filterEdges(func(from, to string) bool {
+		if from == src && to == dep || added[from] {
+			keep[from], keep[to] = true, true
+			edges++
+			return true
+		}
+		return false
+	})
+	g.keepNodes(keep)
+	report("importing %s from %s adds %d packages and %d imports\n", dep, src, len(added), edges)
+
+	for name := range added {
+		g.setNodeAttr(name, "color", "tomato")
+	}
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			g.setEdgeAttr(name, imp, "color", "red")
+		}
+	}
+	g.roots = []string{src}
+	g.setNodeAttr(src, "shape", "box")
+	return g, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"sync"
+	"time"
+)
+
+var profileTop = flag.Int("profile", 0, "print the N packages that took the longest to import on stderr")
+
+var (
+	// importDurations records how long importing each package took when
+	// -profile is given.
+	importDurations   = make(map[string]time.Duration)
+	importDurationsMu sync.Mutex
+)
+
+// recordImport records that importing the named package took d.
+func recordImport(name string, d time.Duration) {
+	importDurationsMu.Lock()
+	importDurations[name] += d
+	importDurationsMu.Unlock()
+}
+
+// reportProfile lists the n packages that took the longest to import on
+// stderr, slowest first.
+func reportProfile(n int) {
+	importDurationsMu.Lock()
+	defer importDurationsMu.Unlock()
+
+	var names []string
+	var total time.Duration
+	for name, d := range importDurations {
+		names = append(names, name)
+		total += d
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if importDurations[names[i]] != importDurations[names[j]] {
+			return importDurations[names[i]] > importDurations[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	if len(names) > n {
+		names = names[:n]
+	}
+	for _, name := range names {
+		report("%10s %s\n", importDurations[name].Round(time.Microsecond), name)
+	}
+	report("%10s total for %d packages\n", total.Round(time.Microsecond), len(importDurations))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writePrometheus writes per-package degree metrics and graph totals in
+// the Prometheus text exposition format.
+func writePrometheus(w io.Writer, g *graph) error {
+	fanin := g.fanin()
+
+	fmt.Fprintln(w, "# HELP godepgraph_package_fanin Number of packages in the graph that import the package.")
+	fmt.Fprintln(w, "# TYPE godepgraph_package_fanin gauge")
+	for _, name := range g.nodes {
+		fmt.Fprintf(w, "godepgraph_package_fanin{package=\"%s\"} %d\n", promEscaper.Replace(name), fanin[name])
+	}
+
+	fmt.Fprintln(w, "# HELP godepgraph_package_fanout Number of packages in the graph imported by the package.")
+	fmt.Fprintln(w, "# TYPE godepgraph_package_fanout gauge")
+	for _, name := range g.nodes {
+		fmt.Fprintf(w, "godepgraph_package_fanout{package=\"%s\"} %d\n", promEscaper.Replace(name), len(g.edges[name]))
+	}
+
+	fmt.Fprintln(w, "# HELP godepgraph_total_packages Number of packages in the graph.")
+	fmt.Fprintln(w, "# TYPE godepgraph_total_packages gauge")
+	fmt.Fprintf(w, "godepgraph_total_packages %d\n", len(g.nodes))
+
+	fmt.Fprintln(w, "# HELP godepgraph_total_edges Number of imports between packages in the graph.")
+	fmt.Fprintln(w, "# TYPE godepgraph_total_edges gauge")
+	fmt.Fprintf(w, "godepgraph_total_edges %d\n", g.numEdges())
+	return nil
+}
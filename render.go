@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var (
+	svgFile = flag.String("svg", "", "render the graph with Graphviz dot and write it to this SVG file instead of printing DOT")
+	pngFile = flag.String("png", "", "render the graph with Graphviz dot and write it to this PNG file instead of printing DOT")
+)
+
+// renderImages renders g with the dot command into each of the files
+// requested by -svg and -png.
+func renderImages(g *graph) error {
+	dot, err := exec.LookPath("dot")
+	if err != nil {
+		return fmt.Errorf("rendering images needs the Graphviz dot command: %s", err)
+	}
+
+	var src bytes.Buffer
+	if err := writeDot(&src, g); err != nil {
+		return err
+	}
+	for _, out := range []struct{ format, file string }{{"svg", *svgFile}, {"png", *pngFile}} {
+		if out.file == "" {
+			continue
+		}
+		var stderr strings.Builder
+		cmd := exec.Command(dot, "-T"+out.format, "-o", out.file)
+		cmd.Stdin = bytes.NewReader(src.Bytes())
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("dot failed to render %s: %s: %s", out.file, err, strings.TrimSpace(stderr.String()))
+		}
+		infof("wrote %s", out.file)
+	}
+	return nil
+}
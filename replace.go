@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+)
+
+var showReplaces = flag.Bool("show-replaces", false, "mark packages provided by a replace directive in the main module's go.mod")
+
+// markReplaced tags the packages in g that come from a module replaced in
+// the go.mod of the root package's module, adding the replacement target
+// as a tooltip.
+func markReplaced(g *graph) error {
+	if len(g.roots) == 0 {
+		return nil
+	}
+	modFile := findGoMod(pkgs[g.roots[0]].Dir)
+	if modFile == "" {
+		warnNoModule(g.roots[0])
+		return nil
+	}
+	data, err := os.ReadFile(modFile)
+	if err != nil {
+		return err
+	}
+	reps := parseReplaces(data)
+
+	for _, name := range g.nodes {
+		r, ok := replacementFor(name, reps)
+		if !ok {
+			continue
+		}
+		target := r.newPath
+		if r.newVersion != "" {
+			target += "@" + r.newVersion
+		}
+		g.addNote(name, "[replaced]")
+		g.setNodeAttr(name, "peripheries", "2")
+		g.setNodeAttr(name, "tooltip", "replaced by "+target)
+	}
+	return nil
+}
+
+// replacementFor returns the replace directive with the longest module
+// path that contains the package importPath.
+func replacementFor(importPath string, reps []replacement) (replacement, bool) {
+	var best replacement
+	found := false
+	for _, r := range reps {
+		if importPath != r.oldPath && !strings.HasPrefix(importPath, r.oldPath+"/") {
+			continue
+		}
+		if !found || len(r.oldPath) > len(best.oldPath) {
+			best, found = r, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	configFile = flag.String("config", "", "read ignore and include rules from this file")
+	printRules = flag.Bool("print-rules", false, "print the effective ignore and include rules and where each one came from, instead of a graph")
+)
+
+// ignoreFileName is the file in the working directory that ignore rules
+// are read from, if it exists.
+const ignoreFileName = ".godepgraphignore"
+
+// ruleSettings are the settings that make up the ignore and include
+// rules, named as in config files.
+var ruleSettings = []string{"ignore", "ignore-prefixes", "only-prefixes"}
+
+// A ruleSource holds the rule settings given by one source. Settings the
+// source doesn't mention are missing from the map.
+type ruleSource struct {
+	name     string
+	settings map[string][]string
+}
+
+// rules holds the resolved value of every rule setting along with the
+// name of the source it was taken from.
+type rules struct {
+	settings map[string][]string
+	sources  map[string]string
+}
+
+// resolveRules takes each setting from the first source that gives it, so
+// sources should be ordered from the highest precedence to the lowest.
+// Settings are never merged across sources.
+func resolveRules(sources []ruleSource) rules {
+	r := rules{settings: make(map[string][]string), sources: make(map[string]string)}
+	for _, setting := range ruleSettings {
+		for _, src := range sources {
+			if values, ok := src.settings[setting]; ok {
+				r.settings[setting] = values
+				r.sources[setting] = src.name
+				break
+			}
+		}
+	}
+	return r
+}
+
+// loadRules resolves the rules given on the command line, in the -config
+// file and in the ignore file in dir, in that order of precedence.
+func loadRules(dir string) (rules, error) {
+	cli := ruleSource{name: "command line", settings: make(map[string][]string)}
+	for setting, value := range map[string]string{
+		"ignore":          *ignorePackages,
+		"ignore-prefixes": *ignorePrefixes,
+		"only-prefixes":   *onlyPrefix,
+	} {
+		if value != "" {
+			cli.settings[setting] = splitList(value)
+		}
+	}
+	sources := []ruleSource{cli}
+
+	if *configFile != "" {
+		src, err := readConfigRules(*configFile)
+		if err != nil {
+			return rules{}, err
+		}
+		sources = append(sources, src)
+	}
+
+	src, err := readIgnoreFile(filepath.Join(dir, ignoreFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return rules{}, err
+	}
+	if err == nil {
+		sources = append(sources, src)
+	}
+	return resolveRules(sources), nil
+}
+
+// readConfigRules reads a config file holding one setting per line, its
+// name followed by a comma-separated list of values. A setting given on
+// several lines collects all of their values.
+func readConfigRules(file string) (ruleSource, error) {
+	lines, err := readList(file)
+	if err != nil {
+		return ruleSource{}, err
+	}
+	src := ruleSource{name: file, settings: make(map[string][]string)}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || !isRuleSetting(fields[0]) {
+			return ruleSource{}, fmt.Errorf("%s: invalid rule %q", file, line)
+		}
+		src.settings[fields[0]] = append(src.settings[fields[0]], splitList(fields[1])...)
+	}
+	return src, nil
+}
+
+// readIgnoreFile reads an ignore file holding one package per line.
+// Entries ending in a slash ignore every package under that prefix.
+func readIgnoreFile(file string) (ruleSource, error) {
+	lines, err := readList(file)
+	if err != nil {
+		return ruleSource{}, err
+	}
+	src := ruleSource{name: ignoreFileName, settings: make(map[string][]string)}
+	for _, line := range lines {
+		if strings.HasSuffix(line, "/") {
+			src.settings["ignore-prefixes"] = append(src.settings["ignore-prefixes"], line)
+		} else {
+			src.settings["ignore"] = append(src.settings["ignore"], line)
+		}
+	}
+	return src, nil
+}
+
+func isRuleSetting(name string) bool {
+	for _, setting := range ruleSettings {
+		if name == setting {
+			return true
+		}
+	}
+	return false
+}
+
+// writeRules writes every rule setting with its values and the source it
+// was taken from.
+func writeRules(w io.Writer, r rules) error {
+	for _, setting := range ruleSettings {
+		values, ok := r.settings[setting]
+		if !ok {
+			fmt.Fprintf(w, "%-16s (not set)\n", setting)
+			continue
+		}
+		fmt.Fprintf(w, "%-16s %s (from %s)\n", setting, strings.Join(values, ","), r.sources[setting])
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadRules(t *testing.T) {
+	tests := []struct {
+		name string
+		// flags holds the values of -i, -p and -o.
+		flags [3]string
+		// config and ignoreFile hold the contents of the -config file
+		// and of the ignore file, if any.
+		config, ignoreFile string
+		want               rules
+	}{
+		{
+			name: "defaults",
+			want: rules{settings: map[string][]string{}, sources: map[string]string{}},
+		},
+		{
+			name:  "flags",
+			flags: [3]string{"a,b", "c/", "d/"},
+			want: rules{
+				settings: map[string][]string{"ignore": {"a", "b"}, "ignore-prefixes": {"c/"}, "only-prefixes": {"d/"}},
+				sources:  map[string]string{"ignore": "command line", "ignore-prefixes": "command line", "only-prefixes": "command line"},
+			},
+		},
+		{
+			name:   "config file",
+			config: "ignore a\nignore-prefixes c/\nonly-prefixes d/,e/\nignore b\n",
+			want: rules{
+				settings: map[string][]string{"ignore": {"a", "b"}, "ignore-prefixes": {"c/"}, "only-prefixes": {"d/", "e/"}},
+				sources:  map[string]string{"ignore": "CONFIG", "ignore-prefixes": "CONFIG", "only-prefixes": "CONFIG"},
+			},
+		},
+		{
+			name:       "ignore file",
+			ignoreFile: "a\nc/\n",
+			want: rules{
+				settings: map[string][]string{"ignore": {"a"}, "ignore-prefixes": {"c/"}},
+				sources:  map[string]string{"ignore": ignoreFileName, "ignore-prefixes": ignoreFileName},
+			},
+		},
+		{
+			name:       "flags over config file over ignore file",
+			flags:      [3]string{"flag", "", ""},
+			config:     "ignore config\nignore-prefixes config/\n",
+			ignoreFile: "file\nfile/\n",
+			want: rules{
+				settings: map[string][]string{"ignore": {"flag"}, "ignore-prefixes": {"config/"}},
+				sources:  map[string]string{"ignore": "command line", "ignore-prefixes": "CONFIG"},
+			},
+		},
+		{
+			name:       "every source gives every setting",
+			flags:      [3]string{"flag", "flag/", "flag/only/"},
+			config:     "ignore config\nignore-prefixes config/\nonly-prefixes config/only/\n",
+			ignoreFile: "file\nfile/\n",
+			want: rules{
+				settings: map[string][]string{"ignore": {"flag"}, "ignore-prefixes": {"flag/"}, "only-prefixes": {"flag/only/"}},
+				sources:  map[string]string{"ignore": "command line", "ignore-prefixes": "command line", "only-prefixes": "command line"},
+			},
+		},
+		{
+			name:       "settings are not merged",
+			config:     "ignore config\n",
+			ignoreFile: "file\nfile/\n",
+			want: rules{
+				settings: map[string][]string{"ignore": {"config"}, "ignore-prefixes": {"file/"}},
+				sources:  map[string]string{"ignore": "CONFIG", "ignore-prefixes": ignoreFileName},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			config := ""
+			if tt.config != "" {
+				config = filepath.Join(dir, "rules.conf")
+				writeFiles(t, dir, map[string]string{"rules.conf": tt.config})
+			}
+			if tt.ignoreFile != "" {
+				writeFiles(t, dir, map[string]string{ignoreFileName: tt.ignoreFile})
+			}
+			setFlag(t, ignorePackages, tt.flags[0])
+			setFlag(t, ignorePrefixes, tt.flags[1])
+			setFlag(t, onlyPrefix, tt.flags[2])
+			setFlag(t, configFile, config)
+
+			got, err := loadRules(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for setting, src := range tt.want.sources {
+				if src == "CONFIG" {
+					tt.want.sources[setting] = config
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("loadRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadRulesInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"rules.conf": "exclude a\n"})
+	setFlag(t, configFile, filepath.Join(dir, "rules.conf"))
+	if _, err := loadRules(dir); err == nil {
+		t.Error("loadRules() succeeded with an unknown setting")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"go/build"
+	"net/http"
+	"sync"
+)
+
+var serveAddr = flag.String("serve", "", "serve graphs as JSON over HTTP on this address, e.g. :8080, instead of rendering one")
+
+// importKey identifies a build.Context.Import call.
+type importKey struct {
+	path, srcDir string
+}
+
+var (
+	// importCache holds the packages imported so far when serving, so
+	// that later requests don't have to import them again.
+	importCache = make(map[importKey]*build.Package)
+
+	// serveMu serializes requests, since each one rebuilds pkgs.
+	serveMu sync.Mutex
+)
+
+// importPackage imports the named package like buildContext.Import, but
+// reuses earlier results when serving.
+func importPackage(path, srcDir string) (*build.Package, error) {
+	if *serveAddr == "" {
+		return buildContext.Import(path, srcDir, 0)
+	}
+	key := importKey{path, srcDir}
+	if pkg, ok := importCache[key]; ok {
+		return pkg, nil
+	}
+	// Like buildContext.Import, return the partial package along with
+	// any error, as processPackage may still make use of it. Such
+	// packages are not cached, so the next request tries again.
+	pkg, err := buildContext.Import(path, srcDir, 0)
+	if err != nil {
+		return pkg, err
+	}
+	importCache[key] = pkg
+	return pkg, nil
+}
+
+// jsonGraph is the response to a graph request.
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+// serve answers requests of the form /graph?pkg=PATH with the dependency
+// graph of the package, resolved relative to root, as JSON.
+func serve(addr, root string) error {
+	http.HandleFunc("/graph", func(w http.ResponseWriter, r *http.Request) {
+		pkgName := r.URL.Query().Get("pkg")
+		if pkgName == "" {
+			http.Error(w, "missing pkg parameter", http.StatusBadRequest)
+			return
+		}
+
+		serveMu.Lock()
+		defer serveMu.Unlock()
+		resetPackages()
+		ids = make(map[string]int)
+		nextId = 0
+
+		debugf("serving graph of %s", pkgName)
+		if err := processRoot(root, pkgName); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		g := newGraph()
+
+		res := jsonGraph{Nodes: []jsonNode{}, Edges: []jsonEdge{}}
+		for _, name := range g.nodes {
+			res.Nodes = append(res.Nodes, newJSONNode(name))
+			for _, imp := range g.edges[name] {
+				res.Edges = append(res.Edges, jsonEdge{From: name, To: imp})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(res); err != nil {
+			warnf("failed to write response: %s", err)
+		}
+	})
+
+	infof("serving on %s", addr)
+	return http.ListenAndServe(addr, nil)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestImportPackagePartial(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/tagged/tagged.go": "//go:build never\n\npackage tagged\n",
+	})
+	useGOPATH(t, dir)
+	for _, addr := range []string{"", ":0"} {
+		setFlag(t, serveAddr, addr)
+		pkg, err := importPackage("ex/tagged", dir)
+		if err == nil {
+			t.Fatalf("-serve %q: importing a package without buildable files succeeded", addr)
+		}
+		if pkg == nil || pkg.Dir != filepath.Join(dir, "src", "ex", "tagged") {
+			t.Errorf("-serve %q: got package %+v along with %v, want the partial package", addr, pkg, err)
+		}
+	}
+	if len(importCache) != 0 {
+		t.Errorf("failed import was cached: %v", importCache)
+	}
+}
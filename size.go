@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var sizeByCompiled = flag.Bool("size-by-compiled", false, "compile the packages in the graph and draw each larger the larger its compiled archive, as an estimate of its share of binary size")
+
+// compiledSizes compiles the packages of g with the go command, through go
+// list -export, and returns the size in bytes of the archive of each, an
+// upper bound on what it adds to a binary.
+func compiledSizes(g *graph, dir string) (map[string]int64, error) {
+	var names []string
+	for _, name := range g.nodes {
+		if pkgs[name].Dir != "" {
+			names = append(names, name)
+		}
+	}
+	args := []string{"list", "-e", "-export", "-f", "{{.ImportPath}}\t{{.Export}}"}
+	if len(buildTags) > 0 {
+		args = append(args, "-tags", strings.Join(buildTags, ","))
+	}
+	cmd := exec.Command("go", append(args, names...)...)
+	cmd.Dir = dir
+	cgo := "0"
+	if buildContext.CgoEnabled {
+		cgo = "1"
+	}
+	cmd.Env = append(os.Environ(), "GOOS="+buildContext.GOOS, "GOARCH="+buildContext.GOARCH, "CGO_ENABLED="+cgo)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("go list -export failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	sizes := make(map[string]int64)
+	for _, line := range strings.Split(string(out), "\n") {
+		parts := strings.SplitN(line, "\t", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			continue
+		}
+		info, err := os.Stat(parts[1])
+		if err != nil {
+			debugf("no archive for %s: %s", parts[0], err)
+			continue
+		}
+		sizes[canonicalPath(parts[0])] = info.Size()
+	}
+	return sizes, nil
+}
+
+// markCompiledSize scales the font of every node of g with the square root
+// of the size of its compiled archive, so that the area of the node grows
+// with the size, and puts the size in its tooltip.
+func markCompiledSize(g *graph, dir string) error {
+	sizes, err := compiledSizes(g, dir)
+	if err != nil {
+		return err
+	}
+	var max int64
+	for _, n := range sizes {
+		if n > max {
+			max = n
+		}
+	}
+	if max == 0 {
+		return nil
+	}
+	for name, n := range sizes {
+		if !g.hasNode(name) {
+			continue
+		}
+		size := 10 + 30*math.Sqrt(float64(n)/float64(max))
+		g.setNodeAttr(name, "fontsize", fmt.Sprintf("%.0f", size))
+		g.setNodeAttr(name, "tooltip", fmt.Sprintf("%d KiB compiled", (n+1023)/1024))
+	}
+	return nil
+}
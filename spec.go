@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+var specFile = flag.String("spec", "", "a file declaring groups of packages and the dependencies allowed between them; other imports between groups are violations")
+
+// A spec is a declared architecture: groups of packages, by import path
+// prefix, and the groups each one may import.
+type spec struct {
+	// prefixes maps each import path prefix to the group it belongs to.
+	prefixes map[string]string
+	// allowed holds the permitted dependencies between distinct groups.
+	allowed map[edge]bool
+}
+
+// readSpec reads a spec file. Each line is either
+//
+//	group NAME PREFIX[,PREFIX...]
+//
+// declaring the packages under the prefixes as the group NAME, or
+//
+//	allow FROM TO[,TO...]
+//
+// permitting the packages of group FROM to import those of the groups TO.
+func readSpec(file string) (*spec, error) {
+	lines, err := readList(file)
+	if err != nil {
+		return nil, err
+	}
+	s := &spec{prefixes: make(map[string]string), allowed: make(map[edge]bool)}
+	groups := make(map[string]bool)
+	var allows [][]string
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s: invalid line %q", file, line)
+		}
+		switch fields[0] {
+		case "group":
+			groups[fields[1]] = true
+			for _, prefix := range splitList(fields[2]) {
+				s.prefixes[prefix] = fields[1]
+			}
+		case "allow":
+			allows = append(allows, fields)
+		default:
+			return nil, fmt.Errorf("%s: invalid line %q", file, line)
+		}
+	}
+	for _, fields := range allows {
+		for _, to := range append([]string{fields[1]}, splitList(fields[2])...) {
+			if !groups[to] {
+				return nil, fmt.Errorf("%s: unknown group %q", file, to)
+			}
+		}
+		for _, to := range splitList(fields[2]) {
+			s.allowed[edge{fields[1], to}] = true
+		}
+	}
+	return s, nil
+}
+
+// groupOf returns the group of the package name by its longest matching
+// prefix, or "" if it is in no group.
+func (s *spec) groupOf(name string) string {
+	group, longest := "", -1
+	for prefix, g := range s.prefixes {
+		if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+			group, longest = g, len(prefix)
+		}
+	}
+	return group
+}
+
+// checkSpec colors every edge of g between two groups of s that s doesn't
+// allow red and reports it. Imports within a group and imports of
+// packages outside of any group are always allowed. It returns the
+// number of violations.
+func checkSpec(g *graph, s *spec) int {
+	violations := 0
+	for _, name := range g.nodes {
+		from := s.groupOf(name)
+		if from == "" {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			to := s.groupOf(imp)
+			if to == "" || to == from || s.allowed[edge{from, to}] {
+				continue
+			}
+			violations++
+			report("spec violation: %s (%s) imports %s (%s)\n", name, from, imp, to)
+			g.setEdgeAttr(name, imp, "color", "red")
+		}
+	}
+	return violations
+}
@@ -0,0 +1,33 @@
+package main
+
+import "flag"
+
+var (
+	stable         = flag.String("stable", "", "a comma-separated list of prefixes of stable packages; their imports of packages that are neither stable nor in the standard library are drawn red and reported")
+	failOnUnstable = flag.Bool("fail-on-unstable", false, "exit with a non-zero status if a stable package imports an unstable one")
+)
+
+// checkStable colors every edge of g from a package under one of the
+// stable prefixes to a package that is under none of them red, and reports
+// it. The standard library counts as stable. It returns the number
+// of such edges.
+func checkStable(g *graph, prefixes []string) int {
+	isStable := func(name string) bool {
+		return pkgs[name].Goroot || hasPrefixes(name, prefixes)
+	}
+	n := 0
+	for _, name := range g.nodes {
+		if !hasPrefixes(name, prefixes) {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			if isStable(imp) {
+				continue
+			}
+			n++
+			g.setEdgeAttr(name, imp, "color", "red")
+			report("stable package %s imports unstable %s\n", name, imp)
+		}
+	}
+	return n
+}
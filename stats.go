@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+)
+
+var (
+	statsFlag = flag.Bool("stats", false, "print a table of per-package metrics instead of a graph")
+	sortBy    = flag.String("sort-by", "fanin", "the metric -stats ranks packages by: fanin, fanout, loc or instability")
+	statsTop  = flag.Int("top", 0, "only list this many packages with -stats")
+)
+
+// packageStats holds the metrics of a package printed by -stats.
+type packageStats struct {
+	name   string
+	fanin  int
+	fanout int
+	loc    int
+}
+
+// instability is the share of a package's couplings that are outgoing:
+// 0 for a package that only gets imported, 1 for one that only imports.
+func (s packageStats) instability() float64 {
+	if s.fanin+s.fanout == 0 {
+		return 0
+	}
+	return float64(s.fanout) / float64(s.fanin+s.fanout)
+}
+
+// statsLess maps the names accepted by -sort-by to orderings that put the
+// package with the highest value of the metric first.
+var statsLess = map[string]func(a, b packageStats) bool{
+	"fanin":       func(a, b packageStats) bool { return a.fanin > b.fanin },
+	"fanout":      func(a, b packageStats) bool { return a.fanout > b.fanout },
+	"loc":         func(a, b packageStats) bool { return a.loc > b.loc },
+	"instability": func(a, b packageStats) bool { return a.instability() > b.instability() },
+}
+
+// writeStats writes a table of the metrics of the packages in g, ranked
+// by the -sort-by metric and cut off after -top rows.
+func writeStats(w io.Writer, g *graph) error {
+	less, ok := statsLess[*sortBy]
+	if !ok {
+		return fmt.Errorf("unknown -sort-by metric %q", *sortBy)
+	}
+
+	fanin := g.fanin()
+	var stats []packageStats
+	for _, name := range g.nodes {
+		loc, err := countLines(pkgs[name])
+		if err != nil {
+			return err
+		}
+		stats = append(stats, packageStats{name: name, fanin: fanin[name], fanout: len(g.edges[name]), loc: loc})
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return less(stats[i], stats[j])
+	})
+	if *statsTop > 0 && len(stats) > *statsTop {
+		stats = stats[:*statsTop]
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "FANIN\tFANOUT\tLOC\tINSTABILITY\t\tPACKAGE")
+	for _, s := range stats {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.2f\t\t%s\n", s.fanin, s.fanout, s.loc, s.instability(), s.name)
+	}
+	return tw.Flush()
+}
+
+// countLines returns the number of lines in the Go files of pkg.
+func countLines(pkg *build.Package) (int, error) {
+	n := 0
+	for _, name := range sourceFiles(pkg) {
+		data, err := os.ReadFile(filepath.Join(pkg.Dir, name))
+		if err != nil {
+			return 0, err
+		}
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"sort"
+)
+
+var stdlibUsage = flag.Bool("stdlib-usage", false, "list the standard library packages in the graph on stderr, by how many first-party packages import them")
+
+// reportStdlibUsage lists the standard library packages of g with the
+// number of packages of the root's module importing each of them, most
+// used first.
+func reportStdlibUsage(g *graph) {
+	isFirstParty := firstParty(g)
+	users := make(map[string]int)
+	for _, name := range g.nodes {
+		if !isFirstParty(name) {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			if pkgs[imp].Goroot {
+				users[imp]++
+			}
+		}
+	}
+
+	var std []string
+	for _, name := range g.nodes {
+		if pkgs[name].Goroot {
+			std = append(std, name)
+		}
+	}
+	sort.SliceStable(std, func(i, j int) bool {
+		return users[std[i]] > users[std[j]]
+	})
+	for _, name := range std {
+		report("%5d %s\n", users[name], name)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"sort"
+)
+
+var stream = flag.Bool("stream", false, "write DOT nodes and edges as packages are discovered instead of once the traversal is done; most other options are ignored")
+
+var (
+	// streamOut is where the graph is streamed to.
+	streamOut io.Writer = os.Stdout
+
+	// pendingEdges holds, while streaming, the importers of each import
+	// path that has not been discovered yet. Their edges are written once
+	// the package it resolves to is, so that no edges lead to packages
+	// that turn out to be ignored.
+	pendingEdges = make(map[string][]string)
+
+	// streamedEdges holds the edges written so far. Two import paths can
+	// turn out to be the same package, making for the same edge.
+	streamedEdges = make(map[edge]bool)
+)
+
+// streamPackage writes the node for pkg, imported as pkgName, if it was
+// newly added to pkgs, along with the edges between it and the packages
+// discovered so far. It is called with pkgsMu held.
+func streamPackage(pkgName string, pkg *build.Package, added bool) {
+	// Either path may be an alias of a package already written, such as
+	// a directory seen under another import path.
+	defer flushPendingEdges(pkgName)
+	defer flushPendingEdges(pkg.ImportPath)
+	if !added {
+		return
+	}
+
+	name := pkg.ImportPath
+	fmt.Fprintf(streamOut, "%s [%s];\n", nodeId(name), baseNodeAttrs(pkg, name))
+	if pkg.Goroot && !*delveGoroot {
+		return
+	}
+	for _, imp := range getImports(pkg) {
+		if _, ok := pkgs[imp]; ok {
+			streamEdge(name, imp)
+		} else {
+			pendingEdges[imp] = append(pendingEdges[imp], name)
+		}
+	}
+}
+
+// flushPendingEdges writes the pending edges into the package that path
+// resolves to, if it has been discovered.
+func flushPendingEdges(path string) {
+	to := canonicalPath(path)
+	if _, ok := pkgs[to]; !ok {
+		return
+	}
+	for _, from := range pendingEdges[path] {
+		streamEdge(from, to)
+	}
+	delete(pendingEdges, path)
+}
+
+// streamEdge writes the edge from -> to, unless it has been written
+// already or is a self-reference that getImports would have dropped.
+func streamEdge(from, to string) {
+	e := edge{from, to}
+	if streamedEdges[e] || (from == to && !*selfEdges) {
+		return
+	}
+	streamedEdges[e] = true
+	fmt.Fprintf(streamOut, "%s -> %s;\n", nodeId(from), nodeId(to))
+}
+
+// streamRoot traverses from pkgName, writing the graph as it goes.
+func streamRoot(root, pkgName string) error {
+	fmt.Fprintln(streamOut, "digraph godep {")
+	writePrelude(streamOut)
+	writeNodeDefaults(streamOut)
+	if *horizontal {
+		fmt.Fprintln(streamOut, `rankdir="LR"`)
+	}
+	if err := processRoot(root, pkgName); err != nil {
+		return err
+	}
+
+	// Imports still pending resolve to packages that are ignored or not
+	// in the graph for another reason.
+	var paths []string
+	for path := range pendingEdges {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		flushPendingEdges(path)
+		if _, ok := pendingEdges[path]; ok {
+			debugf("not streaming the imports of %s, which is not in the graph", path)
+		}
+	}
+	fmt.Fprintln(streamOut, "}")
+	return nil
+}
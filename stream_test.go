@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+func TestStreamMatchesGraph(t *testing.T) {
+	dir := t.TempDir()
+	// ex/app imports ex/link, a symlink to ex/lib, and ex/z, whose import
+	// of ex/lib is pending until ex/lib is found to be ex/link. ex/skip is
+	// ignored, so the edge to it must never be written.
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go": "package app\n\nimport (\n\t_ \"ex/link\"\n\t_ \"ex/skip\"\n\t_ \"ex/z\"\n)\n",
+		"src/ex/z/z.go":     "package z\n\nimport _ \"ex/lib\"\n",
+		"src/ex/lib/lib.go": "package lib\n\nimport _ \"strings\"\n",
+		"src/ex/skip/s.go":  "package skip\n\nimport _ \"ex/lib\"\n",
+	})
+	if err := os.Symlink(filepath.Join(dir, "src", "ex", "lib"), filepath.Join(dir, "src", "ex", "link")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	useGOPATH(t, dir)
+	savedPrefixes := ignoredPrefixes
+	t.Cleanup(func() { ignoredPrefixes = savedPrefixes })
+	ignoredPrefixes = []string{"ex/skip"}
+
+	var buf bytes.Buffer
+	streamOut = &buf
+	setBoolFlag(t, stream, true)
+	t.Cleanup(func() { streamOut = os.Stdout })
+	if err := streamRoot(dir, "ex/app"); err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]string)
+	for name := range ids {
+		names[nodeId(name)] = name
+	}
+	var streamedNodes []string
+	streamed := make(map[string][]string)
+	for _, m := range regexp.MustCompile(`(?m)^(\w+) \[`).FindAllStringSubmatch(buf.String(), -1) {
+		streamedNodes = append(streamedNodes, names[m[1]])
+	}
+	for _, m := range regexp.MustCompile(`(?m)^(\w+) -> (\w+);$`).FindAllStringSubmatch(buf.String(), -1) {
+		streamed[names[m[1]]] = append(streamed[names[m[1]]], names[m[2]])
+	}
+	sort.Strings(streamedNodes)
+	for _, imps := range streamed {
+		sort.Strings(imps)
+	}
+
+	*stream = false
+	resetPackages()
+	g := traverse(t, dir, "ex/app")
+	if !reflect.DeepEqual(streamedNodes, g.nodes) {
+		t.Errorf("streamed nodes %v, want %v as in the graph", streamedNodes, g.nodes)
+	}
+	assertEdges(t, g, streamed)
+	if want := []string{"ex/link"}; !reflect.DeepEqual(streamed["ex/z"], want) {
+		t.Errorf("streamed imports of ex/z = %v, want %v", streamed["ex/z"], want)
+	}
+}
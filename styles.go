@@ -0,0 +1,346 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"hash/fnv"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	boundary        = flag.String("boundary", "", "color edges within packages under this prefix gray, and edges crossing into or out of it red")
+	bidirectional   = flag.Bool("bidirectional", false, "draw packages importing each other as a single red edge with arrows at both ends")
+	edgeImpactFlag  = flag.Bool("edge-impact", false, "draw edges thicker the more packages would become unreachable without them")
+	markVendor      = flag.Bool("mark-vendor-edges", false, "draw imports from outside a vendor tree into it dashed blue")
+	highlightModule = flag.String("highlight-module", "", "color the packages of this module and the packages only reachable through it")
+	marginal        = flag.Bool("marginal", false, "label each import of the root with the number of packages that only it brings in")
+	edgeReasons     = flag.Bool("edge-reasons", false, "label each edge with how the package is imported: by regular files (import), internal tests (test) or external tests (xtest)")
+	edgeProvenance  = flag.Bool("edge-provenance", false, "give each edge a tooltip listing the files, and lines, of the importer that import the package")
+	edgePopularity  = flag.Bool("edge-by-target-popularity", false, "color edges by the number of importers of the package they point at, from red for rarely imported packages to blue for ubiquitous ones")
+	autoColors      = flag.Bool("auto-colors", false, "color packages by their cluster, or by module without clusters, with colors derived from the names")
+	colorSeed       = flag.Int("color-seed", 0, "a seed changing the colors picked by -auto-colors")
+	shadeByClosure  = flag.Bool("shade-by-closure", false, "fill packages more intensely the more packages they depend on, directly or not")
+	stdlibGroups    = flag.Bool("stdlib-groups", false, "color standard library packages by their top-level group (net, crypto, ...)")
+)
+
+// groupPalette holds the fill colors handed out to groups of packages.
+var groupPalette = []string{
+	"palegreen", "lightblue", "khaki", "plum", "lightsalmon", "aquamarine",
+	"lightpink", "wheat", "lightsteelblue", "thistle", "darkseagreen1", "lightgoldenrod",
+}
+
+// markBoundary colors the edges of g by how they relate to the packages
+// under prefix: edges with both ends inside are gray, edges with exactly
+// one end inside are red, and edges entirely outside are left alone.
+func markBoundary(g *graph, prefix string) {
+	inside := func(name string) bool {
+		return name == prefix || strings.HasPrefix(name, strings.TrimSuffix(prefix, "/")+"/")
+	}
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			switch from, to := inside(name), inside(imp); {
+			case from && to:
+				g.setEdgeAttr(name, imp, "color", "gray")
+			case from != to:
+				g.setEdgeAttr(name, imp, "color", "red")
+			}
+		}
+	}
+}
+
+// colorStdlibGroups colors the standard library packages in g by the first
+// element of their import path, so that e.g. everything under net/ shares
+// a color distinct from everything under crypto/.
+func colorStdlibGroups(g *graph) {
+	groups := make(map[string][]string)
+	for _, name := range g.nodes {
+		if pkgs[name].Goroot {
+			group := strings.SplitN(name, "/", 2)[0]
+			groups[group] = append(groups[group], name)
+		}
+	}
+
+	var names []string
+	for group := range groups {
+		names = append(names, group)
+	}
+	sort.Strings(names)
+	for i, group := range names {
+		color := groupPalette[i%len(groupPalette)]
+		for _, name := range groups[group] {
+			g.setNodeAttr(name, "color", color)
+		}
+	}
+}
+
+// mergeBidirectional replaces each pair of packages in g that import each
+// other with a single edge drawn with arrowheads at both ends in red, and
+// logs the pair. Mutual imports can't exist between regular packages, so
+// they usually come from test imports. It returns the number of pairs.
+func mergeBidirectional(g *graph) int {
+	imports := make(map[edge]bool)
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			imports[edge{name, imp}] = true
+		}
+	}
+
+	n := 0
+	g.filterEdges(func(from, to string) bool {
+		if from == to || !imports[edge{to, from}] {
+			return true
+		}
+		if from > to {
+			return false
+		}
+		n++
+		warnf("%s and %s import each other", from, to)
+		g.setEdgeAttr(from, to, "dir", "both")
+		g.setEdgeAttr(from, to, "color", "red")
+		return true
+	})
+	return n
+}
+
+// markEdgeImpact labels every edge of g that some packages are only
+// reachable through with the number of those packages, and scales its
+// width accordingly.
+func markEdgeImpact(g *graph) {
+	impact := edgeImpact(g)
+	max := 0
+	for _, n := range impact {
+		if n > max {
+			max = n
+		}
+	}
+	for e, n := range impact {
+		if n == 0 {
+			continue
+		}
+		g.setEdgeAttr(e.from, e.to, "penwidth", fmt.Sprintf("%.1f", 1+7*float64(n)/float64(max)))
+		g.setEdgeAttr(e.from, e.to, "label", fmt.Sprint(n))
+	}
+}
+
+// markMarginal labels each direct import of the roots of g with the number
+// of packages it brings in that no other direct import of the same root
+// does, including the imported package itself.
+func markMarginal(g *graph) {
+	for _, root := range g.roots {
+		imports := g.edges[root]
+		for i, imp := range imports {
+			var others []string
+			others = append(others, imports[:i]...)
+			others = append(others, imports[i+1:]...)
+			covered := g.reachable(others...)
+			covered[root] = true
+
+			n := 0
+			for name := range g.reachable(imp) {
+				if !covered[name] {
+					n++
+				}
+			}
+			g.setEdgeAttr(root, imp, "label", fmt.Sprint(n))
+		}
+	}
+}
+
+// markEdgeReasons labels every edge of g with the ways in which the
+// importer imports the package, such as "import+test" for a package
+// imported by both the regular files and the tests of another.
+func markEdgeReasons(g *graph) {
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		for _, imp := range g.edges[name] {
+			if reasons := importReasons(pkg, imp); len(reasons) > 0 {
+				g.setEdgeAttr(name, imp, "label", strings.Join(reasons, "+"))
+			}
+		}
+	}
+}
+
+// markEdgeProvenance sets the tooltip of every edge of g to the places in
+// the importer's files that import the package, one file:line per line.
+func markEdgeProvenance(g *graph) {
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		positions := []map[string][]token.Position{pkg.ImportPos}
+		if *includeTests {
+			if *testScope != "external" {
+				positions = append(positions, pkg.TestImportPos)
+			}
+			if *testScope != "internal" {
+				positions = append(positions, pkg.XTestImportPos)
+			}
+		}
+		for _, imp := range g.edges[name] {
+			var places []string
+			for _, m := range positions {
+				for path, ps := range m {
+					if canonicalPath(path) != imp {
+						continue
+					}
+					for _, p := range ps {
+						places = append(places, fmt.Sprintf("%s:%d", filepath.Base(p.Filename), p.Line))
+					}
+				}
+			}
+			if len(places) > 0 {
+				sort.Strings(places)
+				g.setEdgeAttr(name, imp, "tooltip", strings.Join(places, `\n`))
+			}
+		}
+	}
+}
+
+// isVendored reports whether the import path points into a vendor tree.
+func isVendored(name string) bool {
+	return strings.HasPrefix(name, "vendor/") || strings.Contains(name, "/vendor/")
+}
+
+// markVendorEdges styles the edges of g that cross from non-vendored code
+// into a vendor tree, leaving edges among vendored packages alone.
+func markVendorEdges(g *graph) {
+	for _, name := range g.nodes {
+		if isVendored(name) {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			if isVendored(imp) {
+				g.setEdgeAttr(name, imp, "style", "dashed")
+				g.setEdgeAttr(name, imp, "color", "blue")
+			}
+		}
+	}
+}
+
+// markModuleFootprint colors the packages of g that belong to mod, and in
+// a lighter shade the packages that would drop out of the graph along
+// with them if the module were removed. It returns the number of packages
+// in each group.
+func markModuleFootprint(g *graph, mod string) (own, only int) {
+	inModule := make(map[string]bool)
+	for _, name := range g.nodes {
+		if moduleOf(pkgs[name]) == mod {
+			inModule[name] = true
+		}
+	}
+
+	without := make(map[string]bool)
+	stack := append([]string{}, g.roots...)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if without[name] || inModule[name] {
+			continue
+		}
+		without[name] = true
+		stack = append(stack, g.edges[name]...)
+	}
+
+	for _, name := range g.nodes {
+		switch {
+		case inModule[name]:
+			g.setNodeAttr(name, "color", "orange")
+			own++
+		case !without[name]:
+			g.setNodeAttr(name, "color", "lightsalmon")
+			only++
+		}
+	}
+	return own, only
+}
+
+// markAutoColors colors every node of g by the innermost cluster it is
+// drawn in or, outside of clusters, by its module. The colors are derived
+// from a hash of the cluster or module name and seed, so that they are
+// the same from one run to the next.
+func markAutoColors(g *graph, seed int) {
+	groups := make(map[string]string)
+	var walk func(clusters []cluster, prefix string)
+	walk = func(clusters []cluster, prefix string) {
+		for _, c := range clusters {
+			label := prefix + "/" + c.label
+			for _, name := range c.nodes {
+				groups[name] = label
+			}
+			walk(c.clusters, label)
+		}
+	}
+	walk(g.clusters, "")
+
+	for _, name := range g.nodes {
+		group, ok := groups[name]
+		if !ok {
+			pkg := pkgs[name]
+			if pkg.Goroot {
+				group = "std"
+			} else {
+				group = moduleOf(pkg)
+			}
+		}
+		g.setNodeAttr(name, "color", hashColor(group, seed))
+	}
+}
+
+// markEdgePopularity colors every edge of g by the fan-in of the package it
+// points at, on a scale from red for packages with a single importer to
+// blue for the most imported package, so that the unusual dependencies
+// stand out.
+func markEdgePopularity(g *graph) {
+	fanin := g.fanin()
+	max := 1
+	for _, n := range fanin {
+		if n > max {
+			max = n
+		}
+	}
+	for _, name := range g.nodes {
+		for _, imp := range g.edges[name] {
+			p := 0.0
+			if max > 1 {
+				p = float64(fanin[imp]-1) / float64(max-1)
+			}
+			g.setEdgeAttr(name, imp, "color", fmt.Sprintf("%.3f 1.000 0.900", 0.66*p))
+			g.setEdgeAttr(name, imp, "tooltip", fmt.Sprintf("%s has %d importers", imp, fanin[imp]))
+		}
+	}
+}
+
+// markClosureShading fills every node of g with a shade of red whose
+// saturation grows with the number of packages it depends on, directly or
+// not, from pale for a leaf to fully saturated for the heaviest package.
+func markClosureShading(g *graph) {
+	sizes := make(map[string]int)
+	max := 0
+	for _, name := range g.nodes {
+		n := len(g.reachable(name)) - 1
+		sizes[name] = n
+		if n > max {
+			max = n
+		}
+	}
+	if max == 0 {
+		return
+	}
+	for _, name := range g.nodes {
+		sat := 0.05 + 0.95*float64(sizes[name])/float64(max)
+		g.setNodeAttr(name, "color", fmt.Sprintf("0.000 %.3f 1.000", sat))
+		g.setNodeAttr(name, "tooltip", fmt.Sprintf("%d dependencies", sizes[name]))
+	}
+}
+
+// hashColor returns a light DOT color in HSV form derived from name and
+// seed.
+func hashColor(name string, seed int) string {
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%d:%s", seed, name)
+	sum := h.Sum32()
+	hue := float64(sum&0xffff) / 0x10000
+	sat := 0.30 + 0.25*float64(sum>>16&0xff)/0xff
+	return fmt.Sprintf("%.3f %.3f 1.000", hue, sat)
+}
@@ -0,0 +1,21 @@
+package main
+
+import "testing"
+
+func TestMarkEdgeReasons(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go":      "package app\n\nimport _ \"ex/lib\"\n",
+		"src/ex/app/app_test.go": "package app\n\nimport _ \"ex/lib\"\n",
+		"src/ex/lib/lib.go":      "package lib\n",
+	})
+	useGOPATH(t, dir)
+	setBoolFlag(t, includeTests, true)
+
+	g := traverse(t, dir, "ex/app")
+	assertEdges(t, g, map[string][]string{"ex/app": {"ex/lib"}})
+	markEdgeReasons(g)
+	if got := g.edgeAttrs[edge{"ex/app", "ex/lib"}].String(); got != `label="import+test"` {
+		t.Errorf("edge attributes = %s, want label=\"import+test\"", got)
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+)
+
+var (
+	testOnlyDeps = flag.Bool("test-only-deps", false, "list the packages only reachable through test imports on stderr, with the package whose tests pull each in (implies -t)")
+	testClosure  = flag.String("test-closure", "", "graph what running the tests of this package depends on: the package and everything its tests import, directly or not")
+)
+
+// testOnlyPackages returns the packages of g that are only reachable from
+// its roots through test imports, each mapped to the package whose tests
+// pull it in.
+func testOnlyPackages(g *graph) map[string]string {
+	prod := make(map[string]bool)
+	stack := append([]string{}, g.roots...)
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if prod[name] {
+			continue
+		}
+		prod[name] = true
+		for _, imp := range g.edges[name] {
+			if !isTestImport(pkgs[name], imp) {
+				stack = append(stack, imp)
+			}
+		}
+	}
+
+	// Walk breadth first from the edges leaving the production packages
+	// through tests, so that each package is attributed to the tests
+	// closest to the roots.
+	pulledBy := make(map[string]string)
+	var queue []string
+	for _, name := range g.nodes {
+		if !prod[name] {
+			continue
+		}
+		for _, imp := range g.edges[name] {
+			if _, ok := pulledBy[imp]; !ok && !prod[imp] {
+				pulledBy[imp] = name
+				queue = append(queue, imp)
+			}
+		}
+	}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, imp := range g.edges[name] {
+			if _, ok := pulledBy[imp]; !ok && !prod[imp] {
+				pulledBy[imp] = pulledBy[name]
+				queue = append(queue, imp)
+			}
+		}
+	}
+	return pulledBy
+}
+
+// reportTestOnlyDeps lists the packages of g only reachable through test
+// imports on stderr.
+func reportTestOnlyDeps(g *graph) {
+	pulledBy := testOnlyPackages(g)
+	for _, name := range g.nodes {
+		if by, ok := pulledBy[name]; ok {
+			report("%s (pulled in by the tests of %s)\n", name, by)
+		}
+	}
+	report("%d packages are only reachable through test imports\n", len(pulledBy))
+}
+
+// testClosureGraph builds the graph of pkgName together with the imports
+// of its tests and everything they import in turn, but not the tests of
+// any other package. The imports made only by the tests are drawn dashed.
+func testClosureGraph(root, pkgName string) (*graph, error) {
+	if err := processRoot(root, pkgName); err != nil {
+		return nil, err
+	}
+	pkg, err := buildContext.Import(pkgName, root, build.FindOnly)
+	if err != nil {
+		return nil, err
+	}
+	name := canonicalPath(resolvedImportPath(pkg))
+	pkg = pkgs[name]
+	if pkg == nil {
+		return nil, fmt.Errorf("package %s is ignored", pkgName)
+	}
+	for _, imp := range testImports(pkg) {
+		if _, ok := pkgs[canonicalPath(imp)]; !ok {
+			if err := processPackage(root, imp); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	g := newGraph()
+	for _, imp := range testImports(pkg) {
+		imp = canonicalPath(imp)
+		if !g.hasNode(imp) || imp == name && !*selfEdges {
+			continue
+		}
+		edge := false
+		for _, i := range g.edges[name] {
+			edge = edge || i == imp
+		}
+		if !edge {
+			g.edges[name] = append(g.edges[name], imp)
+		}
+		if isTestImport(pkg, imp) {
+			g.setEdgeAttr(name, imp, "style", "dashed")
+		}
+	}
+	return g, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"sync"
+)
+
+var timeout = flag.Duration("timeout", 0, "give up on the traversal after this long and render the partial graph")
+
+var (
+	// traversal is checked by processPackage before importing each
+	// package; it is cancelled when -timeout expires.
+	traversal = context.Background()
+
+	// pkgsMu guards every access processPackage makes to pkgs and the
+	// related maps while a traversal runs in the background.
+	pkgsMu sync.Mutex
+)
+
+// processRootWithTimeout runs processRoot in the background and waits at
+// most -timeout for it. It reports whether the traversal was cut short;
+// in that case pkgs holds whatever was collected until then and is no
+// longer modified.
+func processRootWithTimeout(root, pkgName string) (incomplete bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	traversal = ctx
+
+	done := make(chan error, 1)
+	go func() {
+		done <- processRoot(root, pkgName)
+	}()
+
+	select {
+	case err = <-done:
+		if err == nil || err != ctx.Err() {
+			return false, err
+		}
+	case <-ctx.Done():
+	}
+
+	// Wait for an access in progress to finish; later ones will see
+	// that the context is done and back off.
+	pkgsMu.Lock()
+	pkgsMu.Unlock()
+	warnf("traversal timed out after %s, graph is incomplete", *timeout)
+	return true, nil
+}
+
+// withPkgs calls fn with pkgsMu held, unless the traversal has been
+// cancelled, in which case it returns the cancellation error and leaves
+// the shared maps alone.
+func withPkgs(fn func()) error {
+	pkgsMu.Lock()
+	defer pkgsMu.Unlock()
+	if err := traversal.Err(); err != nil {
+		return err
+	}
+	fn()
+	return nil
+}
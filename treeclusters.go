@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+var treeClusters = flag.Bool("tree-clusters", false, "draw nested clusters mirroring the import path hierarchy")
+
+// A pathTrie holds the import paths of a graph by their path elements.
+type pathTrie struct {
+	// pkg is set if the path leading here is a package in the graph.
+	pkg      bool
+	children map[string]*pathTrie
+}
+
+func (t *pathTrie) insert(elems []string) {
+	if len(elems) == 0 {
+		t.pkg = true
+		return
+	}
+	if t.children == nil {
+		t.children = make(map[string]*pathTrie)
+	}
+	child, ok := t.children[elems[0]]
+	if !ok {
+		child = &pathTrie{}
+		t.children[elems[0]] = child
+	}
+	child.insert(elems[1:])
+}
+
+// contents returns the nodes and clusters below the path prefix in t.
+// Packages without packages below them are plain nodes; every other path
+// element becomes a cluster, except that a cluster holding nothing but a
+// single nested cluster is merged with it.
+func (t *pathTrie) contents(prefix string) (nodes []string, clusters []cluster) {
+	var elems []string
+	for elem := range t.children {
+		elems = append(elems, elem)
+	}
+	sort.Strings(elems)
+
+	for _, elem := range elems {
+		child := t.children[elem]
+		path := elem
+		if prefix != "" {
+			path = prefix + "/" + elem
+		}
+		if len(child.children) == 0 {
+			nodes = append(nodes, path)
+			continue
+		}
+
+		c := cluster{label: elem}
+		if child.pkg {
+			c.nodes = append(c.nodes, path)
+		}
+		subNodes, subClusters := child.contents(path)
+		c.nodes = append(c.nodes, subNodes...)
+		c.clusters = subClusters
+		if len(c.nodes) == 0 && len(c.clusters) == 1 {
+			inner := c.clusters[0]
+			inner.label = elem + "/" + inner.label
+			c = inner
+		}
+		clusters = append(clusters, c)
+	}
+	return nodes, clusters
+}
+
+// clusterTree draws the packages of g in clusters nested like their
+// import paths.
+func clusterTree(g *graph) {
+	root := &pathTrie{}
+	for _, name := range g.nodes {
+		root.insert(strings.Split(name, "/"))
+	}
+	_, g.clusters = root.contents("")
+}
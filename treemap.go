@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	treemapWidth  = 1200
+	treemapHeight = 800
+)
+
+// A treemapItem is a weighted rectangle in a treemap, with the items
+// nested inside it. Modules and directories hold items; packages don't.
+type treemapItem struct {
+	label    string
+	weight   int
+	children []*treemapItem
+
+	// dirs holds the directories among children by their path element.
+	// It is nil for packages.
+	dirs map[string]*treemapItem
+}
+
+// add adds the package name of weight lines to t, in the directories
+// elems below it, and counts its lines towards each of them.
+func (t *treemapItem) add(elems []string, name string, weight int) {
+	t.weight += weight
+	if len(elems) == 0 {
+		t.children = append(t.children, &treemapItem{label: name, weight: weight})
+		return
+	}
+	dir, ok := t.dirs[elems[0]]
+	if !ok {
+		dir = &treemapItem{label: elems[0], dirs: make(map[string]*treemapItem)}
+		t.dirs[elems[0]] = dir
+		t.children = append(t.children, dir)
+	}
+	dir.add(elems[1:], name, weight)
+}
+
+// simplifyTreemap replaces each directory among items that holds a single
+// item with that item, joining the labels of nested directories, and
+// orders the items at every level.
+func simplifyTreemap(items []*treemapItem) {
+	for i, item := range items {
+		for item.dirs != nil && len(item.children) == 1 {
+			only := item.children[0]
+			if only.dirs != nil {
+				only.label = item.label + "/" + only.label
+			}
+			item = only
+		}
+		items[i] = item
+		simplifyTreemap(item.children)
+	}
+	sortTreemap(items)
+}
+
+// buildTreemap returns the items of the treemap of g: one per module,
+// holding the directories below the module root and, within them, the
+// packages weighted by their lines of code. Standard library packages are
+// grouped as "std".
+func buildTreemap(g *graph) ([]*treemapItem, error) {
+	byModule := make(map[string]*treemapItem)
+	var modules []*treemapItem
+	for _, name := range g.nodes {
+		pkg := pkgs[name]
+		loc, err := countLines(pkg)
+		if err != nil {
+			return nil, err
+		}
+		if loc == 0 {
+			continue
+		}
+		mod := "std"
+		if !pkg.Goroot {
+			mod = moduleOf(pkg)
+		}
+		m, ok := byModule[mod]
+		if !ok {
+			m = &treemapItem{label: mod, dirs: make(map[string]*treemapItem)}
+			byModule[mod] = m
+			modules = append(modules, m)
+		}
+		// The packages of std and GOPATH have no module path to strip.
+		rel := name
+		if mod != "std" && mod != gopathModule {
+			rel = strings.TrimPrefix(strings.TrimPrefix(name, mod), "/")
+		}
+		var dirs []string
+		if rel != "" {
+			dirs = strings.Split(rel, "/")
+		}
+		m.add(dirs, name, loc)
+	}
+	for _, m := range modules {
+		simplifyTreemap(m.children)
+	}
+	sortTreemap(modules)
+	return modules, nil
+}
+
+// writeTreemap writes the graph as an SVG treemap: one rectangle per module,
+// holding nested rectangles for its directories and one rectangle per
+// package, with areas proportional to the lines of code in the packages.
+func writeTreemap(w io.Writer, g *graph) error {
+	modules, err := buildTreemap(g)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" font-family=\"sans-serif\" font-size=\"11\">\n", treemapWidth, treemapHeight)
+	layoutTreemap(modules, 0, 0, treemapWidth, treemapHeight, true, func(m *treemapItem, x, y, width, h float64) {
+		color := "paleturquoise"
+		if m.label == "std" {
+			color = "palegreen"
+		}
+		drawTreemapGroup(w, m, x, y, width, h, 2, color)
+	})
+	fmt.Fprintln(w, "</svg>")
+	return nil
+}
+
+// drawTreemapGroup draws the module or directory item with an outline of
+// the given stroke width, and the items inside it.
+func drawTreemapGroup(w io.Writer, item *treemapItem, x, y, width, h float64, stroke int, color string) {
+	fmt.Fprintf(w, "<g><title>%s (%d lines)</title>\n", html.EscapeString(item.label), item.weight)
+	fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"white\" stroke=\"black\" stroke-width=\"%d\"/>\n", x, y, width, h, stroke)
+	fmt.Fprintln(w, "</g>")
+	layoutTreemap(item.children, x+2, y+16, width-4, h-18, width-4 < h-18, func(p *treemapItem, x, y, width, h float64) {
+		if p.dirs != nil {
+			drawTreemapGroup(w, p, x, y, width, h, 1, color)
+			return
+		}
+		fmt.Fprintf(w, "<g><title>%s (%d lines)</title>\n", html.EscapeString(p.label), p.weight)
+		fmt.Fprintf(w, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%s\" stroke=\"gray\"/>\n", x, y, width, h, color)
+		if width > 60 && h > 14 {
+			fmt.Fprintf(w, "<text x=\"%.1f\" y=\"%.1f\">%s</text>\n", x+2, y+12, html.EscapeString(p.label))
+		}
+		fmt.Fprintln(w, "</g>")
+	})
+	if width > 60 {
+		fmt.Fprintf(w, "<text x=\"%.1f\" y=\"%.1f\" font-weight=\"bold\">%s</text>\n", x+3, y+12, html.EscapeString(item.label))
+	}
+}
+
+// sortTreemap orders items by decreasing weight, then by label.
+func sortTreemap(items []*treemapItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].weight != items[j].weight {
+			return items[i].weight > items[j].weight
+		}
+		return items[i].label < items[j].label
+	})
+}
+
+// layoutTreemap divides the rectangle at x, y of size w by h among items
+// in proportion to their weights, in strips side by side if horizontal is
+// set and stacked otherwise, and calls draw for each of them.
+func layoutTreemap(items []*treemapItem, x, y, w, h float64, horizontal bool, draw func(item *treemapItem, x, y, w, h float64)) {
+	total := 0
+	for _, item := range items {
+		total += item.weight
+	}
+	if total == 0 || w <= 0 || h <= 0 {
+		return
+	}
+	for _, item := range items {
+		share := float64(item.weight) / float64(total)
+		if horizontal {
+			draw(item, x, y, w*share, h)
+			x += w * share
+		} else {
+			draw(item, x, y, w, h*share)
+			y += h * share
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLayoutTreemap(t *testing.T) {
+	items := []*treemapItem{{label: "a", weight: 6}, {label: "b", weight: 3}, {label: "c", weight: 1}}
+	for _, horizontal := range []bool{true, false} {
+		areas := make(map[string]float64)
+		covered := 0.0
+		layoutTreemap(items, 10, 20, 200, 50, horizontal, func(item *treemapItem, x, y, w, h float64) {
+			if x < 10 || y < 20 || x+w > 210+1e-9 || y+h > 70+1e-9 {
+				t.Errorf("%s at %v,%v sized %vx%v is outside of the rectangle", item.label, x, y, w, h)
+			}
+			areas[item.label] = w * h
+			covered += w * h
+		})
+		for _, item := range items {
+			want := 200 * 50 * float64(item.weight) / 10
+			if math.Abs(areas[item.label]-want) > 1e-6 {
+				t.Errorf("horizontal=%v: area of %s = %v, want %v", horizontal, item.label, areas[item.label], want)
+			}
+		}
+		if math.Abs(covered-200*50) > 1e-6 {
+			t.Errorf("horizontal=%v: items cover %v, want %v", horizontal, covered, 200*50)
+		}
+	}
+}
+
+func TestBuildTreemap(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go":     "package app\n\nimport (\n\t_ \"ex/app/sub\"\n\t_ \"ex/lib/a/b\"\n\t_ \"ex/lib/c\"\n)\n",
+		"src/ex/app/sub/sub.go": "package sub\n",
+		"src/ex/lib/a/b/b.go":   "package b\n\n\n",
+		"src/ex/lib/c/c.go":     "package c\n\n\n\n",
+	})
+	useGOPATH(t, dir)
+	g := traverse(t, dir, "ex/app")
+
+	modules, err := buildTreemap(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump func(items []*treemapItem) string
+	dump = func(items []*treemapItem) string {
+		var parts []string
+		for _, item := range items {
+			part := fmt.Sprintf("%s:%d", item.label, item.weight)
+			if item.dirs != nil {
+				part += "(" + dump(item.children) + ")"
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, " ")
+	}
+	// Each directory weighs the lines of the packages in it. ex/app/sub
+	// and ex/lib/a hold nothing but a single package and are merged
+	// with it.
+	want := "GOPATH:15(ex:15(app:8(ex/app:7 ex/app/sub:1) lib:7(ex/lib/c:4 ex/lib/a/b:3)))"
+	if got := dump(modules); got != want {
+		t.Errorf("treemap = %s\nwant      %s", got, want)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/build"
+	"io"
+)
+
+var unreachablePattern = flag.String("unreachable", "", "print the packages matching this pattern that are not reachable from the root, instead of a graph")
+
+// writeUnreachable writes the import paths of the packages matching
+// pattern that were not reached while traversing from the root. Packages
+// excluded by the ignore rules are left out, as the traversal would skip
+// them even if they were reached.
+func writeUnreachable(w io.Writer, root, pattern string) error {
+	paths, err := expandPattern(root, pattern)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if _, ok := pkgs[canonicalPath(path)]; ok {
+			continue
+		}
+		if isIgnored(&build.Package{ImportPath: path}) {
+			debugf("not listing %s as unreachable, it is ignored", path)
+			continue
+		}
+		fmt.Fprintln(w, path)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteUnreachable(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/ex/app/app.go":         "package app\n\nimport _ \"ex/lib\"\n",
+		"src/ex/lib/lib.go":         "package lib\n",
+		"src/ex/dead/dead.go":       "package dead\n",
+		"src/ex/skip/skip.go":       "package skip\n",
+		"src/ex/gen/proto/proto.go": "package proto\n",
+	})
+	useGOPATH(t, dir)
+	savedIgnored, savedPrefixes := ignored, ignoredPrefixes
+	t.Cleanup(func() { ignored, ignoredPrefixes = savedIgnored, savedPrefixes })
+	ignored = map[string]bool{"C": true, "ex/skip": true}
+	ignoredPrefixes = []string{"ex/gen/"}
+
+	traverse(t, dir, "ex/app")
+	var buf strings.Builder
+	if err := writeUnreachable(&buf, dir, "ex/..."); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "ex/dead\n"; got != want {
+		t.Errorf("unreachable packages = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+var showVersion = flag.Bool("version", false, "print the version of godepgraph and exit")
+
+// version is the godepgraph version. Release builds can set it with
+// -ldflags "-X main.version=..."; otherwise the module version from the
+// build info is used when available.
+var version = "devel"
+
+// versionRequested reports whether -version was given, in which case it
+// writes the version to w and nothing else needs to be done. It runs
+// before any other flag or the package arguments are checked.
+func versionRequested(w io.Writer) bool {
+	if !*showVersion {
+		return false
+	}
+	writeVersion(w)
+	return true
+}
+
+// writeVersion writes the version of godepgraph along with the Go version
+// and VCS revision it was built from.
+func writeVersion(w io.Writer) {
+	v := version
+	goVersion := runtime.Version()
+	var revision, modified string
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "devel" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+		goVersion = info.GoVersion
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				if s.Value == "true" {
+					modified = " (modified)"
+				}
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "godepgraph %s\n", v)
+	fmt.Fprintf(w, "built with %s\n", goVersion)
+	if revision != "" {
+		fmt.Fprintf(w, "revision %s%s\n", revision, modified)
+	}
+}
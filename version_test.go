@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestVersionWithoutPackage(t *testing.T) {
+	out, err := runMain(t, "", "-version")
+	if err != nil {
+		t.Fatalf("godepgraph -version failed: %s\n%s", err, out)
+	}
+	if !strings.HasPrefix(out, "godepgraph ") {
+		t.Errorf("godepgraph -version printed %q, want the version", out)
+	}
+
+	if out, err := runMain(t, ""); err == nil || !strings.Contains(out, "need one package name") {
+		t.Errorf("godepgraph without arguments = %v, %q; want it to ask for a package", err, out)
+	}
+}
+
+func TestVersionRequested(t *testing.T) {
+	setBoolFlag(t, showVersion, false)
+	var buf strings.Builder
+	if versionRequested(&buf) || buf.Len() > 0 {
+		t.Errorf("versionRequested() without -version = true, %q", buf.String())
+	}
+	*showVersion = true
+	if !versionRequested(&buf) || !strings.HasPrefix(buf.String(), "godepgraph ") {
+		t.Errorf("versionRequested() with -version = false, %q", buf.String())
+	}
+}
+
+// runMain runs godepgraph with args in a child process, in dir unless it
+// is empty, and returns its combined output.
+func runMain(t *testing.T, dir string, args ...string) (string, error) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GODEPGRAPH_RUN_MAIN=1")
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var why = flag.String("why", "", "print the shortest import chain from the root to this package, instead of a graph")
+
+// writeWhy writes the shortest chain of imports through which the root of
+// g pulls in pkgName, one package per line.
+func writeWhy(w io.Writer, g *graph, pkgName string) error {
+	path := g.shortestPath(pkgName, g.roots...)
+	if path == nil {
+		return fmt.Errorf("package %s is not imported by %s", pkgName, strings.Join(g.roots, ", "))
+	}
+	for _, name := range path {
+		fmt.Fprintln(w, name)
+	}
+	return nil
+}
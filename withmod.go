@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// stringsFlag is a flag that can be given several times, collecting all
+// of its values.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+var withModules stringsFlag
+
+func init() {
+	flag.Var(&withModules, "with", "resolve the graph as if go.mod required MODULE@VERSION; may be given several times")
+}
+
+// useModuleVersions makes the go command, which go/build runs to resolve
+// imports in module mode, use a temporary copy of the go.mod of the
+// module dir is in, edited to require the -with module versions. It
+// returns a function removing the copy.
+func useModuleVersions(dir string) (func(), error) {
+	modFile := findGoMod(dir)
+	if modFile == "" {
+		return nil, fmt.Errorf("-with needs %s to be in a module", dir)
+	}
+	tmp, err := os.MkdirTemp("", "godepgraph")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+
+	tmpMod := filepath.Join(tmp, "go.mod")
+	if err := copyFile(modFile, tmpMod); err != nil {
+		cleanup()
+		return nil, err
+	}
+	// The go command looks for the checksums of a -modfile next to it.
+	sumFile := strings.TrimSuffix(modFile, ".mod") + ".sum"
+	if err := copyFile(sumFile, filepath.Join(tmp, "go.sum")); err != nil && !os.IsNotExist(err) {
+		cleanup()
+		return nil, err
+	}
+
+	args := []string{"mod", "edit"}
+	for _, mv := range withModules {
+		if !strings.Contains(mv, "@") {
+			cleanup()
+			return nil, fmt.Errorf("-with needs MODULE@VERSION, got %q", mv)
+		}
+		args = append(args, "-require="+mv)
+	}
+	cmd := exec.Command("go", append(args, tmpMod)...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		cleanup()
+		return nil, fmt.Errorf("go mod edit failed: %s: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	flags := strings.TrimSpace(os.Getenv("GOFLAGS") + " -mod=mod -modfile=" + tmpMod)
+	if err := os.Setenv("GOFLAGS", flags); err != nil {
+		cleanup()
+		return nil, err
+	}
+	debugf("resolving with GOFLAGS=%s", flags)
+	return cleanup, nil
+}
+
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, 0644)
+}
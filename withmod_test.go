@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWithCleansUpOnFatal(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":  "module example.com/app\n\ngo 1.18\n",
+		"main.go": "package main\n\nfunc main() {}\n",
+	})
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("GOFLAGS", "")
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GO111MODULE", "on")
+
+	// The missing package makes godepgraph fail after -with set up its
+	// temporary go.mod.
+	out, err := runMain(t, dir, "-with", "example.com/dep@v1.0.0", "example.com/app/missing")
+	if err == nil {
+		t.Fatalf("godepgraph succeeded:\n%s", out)
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("%s was left behind in the temporary directory; output:\n%s", e.Name(), out)
+	}
+}
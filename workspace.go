@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var workspace = flag.Bool("workspace", false, "draw each module of the go.work workspace as a labelled cluster")
+
+// workspaceModules returns the paths of the modules used by the go.work
+// workspace that dir is in, in the order go.work lists them.
+func workspaceModules(dir string) ([]string, error) {
+	workFile := findGoWork(dir)
+	if workFile == "" {
+		return nil, fmt.Errorf("%s is not in a go.work workspace", dir)
+	}
+	data, err := os.ReadFile(workFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var mods []string
+	for _, use := range parseWorkUses(data) {
+		if !filepath.IsAbs(use) {
+			use = filepath.Join(filepath.Dir(workFile), use)
+		}
+		data, err := os.ReadFile(filepath.Join(use, "go.mod"))
+		if err != nil {
+			return nil, err
+		}
+		if mod := modulePath(data); mod != "" {
+			mods = append(mods, mod)
+		}
+	}
+	return mods, nil
+}
+
+// clusterWorkspace groups the packages of g that belong to a module of
+// the workspace that dir is in into one cluster per module.
+func clusterWorkspace(g *graph, dir string) error {
+	mods, err := workspaceModules(dir)
+	if err != nil {
+		return err
+	}
+	members := make(map[string][]string)
+	for _, name := range g.nodes {
+		if mod := moduleOf(pkgs[name]); mod != "" {
+			members[mod] = append(members[mod], name)
+		}
+	}
+	for _, mod := range mods {
+		if len(members[mod]) > 0 {
+			g.clusters = append(g.clusters, cluster{label: mod, nodes: members[mod]})
+		}
+	}
+	return nil
+}
+
+// fixWorkspaceFlags makes imports resolve across the modules of the go.work
+// workspace that dir is in, if any. go/build asks the go command, which
+// understands workspaces but refuses to run in one when GOFLAGS asks for
+// a -mod other than readonly or vendor, so such a flag is dropped.
+func fixWorkspaceFlags(dir string) error {
+	if findGoWork(dir) == "" {
+		return nil
+	}
+	var flags []string
+	dropped := false
+	for _, f := range strings.Fields(os.Getenv("GOFLAGS")) {
+		switch f {
+		case "-mod=readonly", "-mod=vendor":
+		default:
+			if strings.HasPrefix(f, "-mod=") || strings.HasPrefix(f, "--mod=") {
+				dropped = true
+				continue
+			}
+		}
+		flags = append(flags, f)
+	}
+	if !dropped {
+		return nil
+	}
+	debugf("dropping -mod from GOFLAGS in workspace mode")
+	return os.Setenv("GOFLAGS", strings.Join(flags, " "))
+}
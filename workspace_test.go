@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceImports(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.work":         "go 1.18\n\nuse (\n\t./ma\n\t./mb\n)\n",
+		"ma/go.mod":       "module example.com/ma\n\ngo 1.18\n",
+		"ma/cmd/main.go":  "package main\n\nimport _ \"example.com/mb/util\"\n\nfunc main() {}\n",
+		"mb/go.mod":       "module example.com/mb\n\ngo 1.18\n",
+		"mb/util/util.go": "package util\n",
+	})
+	useModules(t)
+	t.Setenv("GOFLAGS", "-mod=mod")
+	chdir(t, filepath.Join(dir, "ma"))
+
+	if err := fixWorkspaceFlags(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := os.Getenv("GOFLAGS"); got != "" {
+		t.Errorf("GOFLAGS = %q, want it emptied", got)
+	}
+	g := traverse(t, filepath.Join(dir, "ma"), "example.com/ma/cmd")
+	assertEdges(t, g, map[string][]string{"example.com/ma/cmd": {"example.com/mb/util"}})
+	if got := moduleOf(pkgs["example.com/mb/util"]); got != "example.com/mb" {
+		t.Errorf("module of example.com/mb/util = %q, want example.com/mb", got)
+	}
+}